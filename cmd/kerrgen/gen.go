@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Catalog 错误码定义文件
+type Catalog struct {
+	Package       string       `yaml:"package" toml:"package" json:"package"`
+	DefaultLocale string       `yaml:"default_locale" toml:"default_locale" json:"default_locale,omitempty"`
+	Range         *CodeRange   `yaml:"range" toml:"range" json:"range,omitempty"`
+	Errors        []Definition `yaml:"errors" toml:"errors" json:"errors"`
+}
+
+// CodeRange 声明的错误码区间，闭区间 [Min, Max]
+type CodeRange struct {
+	Min int `yaml:"min" toml:"min" json:"min"`
+	Max int `yaml:"max" toml:"max" json:"max"`
+}
+
+// Definition 单个错误码定义
+type Definition struct {
+	Name       string            `yaml:"name" toml:"name" json:"name"`
+	Code       int               `yaml:"code" toml:"code" json:"code"`
+	Message    string            `yaml:"message" toml:"message" json:"message"`
+	Display    map[string]string `yaml:"display" toml:"display" json:"display,omitempty"`
+	HTTPStatus int               `yaml:"http_status" toml:"http_status" json:"http_status,omitempty"`
+	Retryable  *bool             `yaml:"retryable" toml:"retryable" json:"retryable,omitempty"`
+}
+
+// Load 读取定义文件，按扩展名识别 YAML（.yaml/.yml）或 TOML（.toml）
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, filepath.Ext(path))
+}
+
+// Parse 按格式解析定义文件内容，ext 为 .yaml/.yml/.toml
+func Parse(data []byte, ext string) (*Catalog, error) {
+	c := &Catalog{}
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("parse yaml: %w", err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("parse toml: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported definition format %q, want .yaml/.yml/.toml", ext)
+	}
+	return c, nil
+}
+
+// Validate 校验定义，返回全部问题；错误码或名称重复、错误码超出声明区间均视为错误
+func (c *Catalog) Validate() []error {
+	var errs []error
+	if !token.IsIdentifier(c.Package) {
+		errs = append(errs, fmt.Errorf("invalid package name %q", c.Package))
+	}
+	if c.Range != nil && c.Range.Min > c.Range.Max {
+		errs = append(errs, fmt.Errorf("invalid range [%d, %d]", c.Range.Min, c.Range.Max))
+	}
+
+	codes := make(map[int]string, len(c.Errors))
+	names := make(map[string]bool, len(c.Errors))
+	for _, d := range c.Errors {
+		if !token.IsIdentifier(d.Name) || !token.IsExported(d.Name) {
+			errs = append(errs, fmt.Errorf("%s: name must be an exported Go identifier", d.label()))
+		}
+		if names[d.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate name", d.label()))
+		}
+		names[d.Name] = true
+
+		if other, ok := codes[d.Code]; ok {
+			errs = append(errs, fmt.Errorf("%s: duplicate code, already used by %s", d.label(), other))
+		} else {
+			codes[d.Code] = d.Name
+		}
+		if c.Range != nil && (d.Code < c.Range.Min || d.Code > c.Range.Max) {
+			errs = append(errs, fmt.Errorf("%s: code out of declared range [%d, %d]", d.label(), c.Range.Min, c.Range.Max))
+		}
+		if d.Message == "" {
+			errs = append(errs, fmt.Errorf("%s: message is required", d.label()))
+		}
+		if d.HTTPStatus != 0 && (d.HTTPStatus < 100 || d.HTTPStatus > 599) {
+			errs = append(errs, fmt.Errorf("%s: invalid http status %d", d.label(), d.HTTPStatus))
+		}
+	}
+	return errs
+}
+
+// label 返回用于提示信息的错误码标识
+func (d Definition) label() string {
+	return fmt.Sprintf("%s(%d)", d.Name, d.Code)
+}
+
+// Locales 返回定义中出现的所有语言，默认语言排在最前，其余按字母序
+func (c *Catalog) Locales() []string {
+	seen := make(map[string]bool)
+	var locales []string
+	for _, d := range c.Errors {
+		for locale := range d.Display {
+			if !seen[locale] && locale != c.DefaultLocale {
+				seen[locale] = true
+				locales = append(locales, locale)
+			}
+		}
+	}
+	sort.Strings(locales)
+	if c.DefaultLocale != "" {
+		locales = append([]string{c.DefaultLocale}, locales...)
+	}
+	return locales
+}
+
+// display 返回默认语言的显示信息，未配置时依次回退到任意语言和 message
+func (c *Catalog) display(d Definition) string {
+	if v, ok := d.Display[c.DefaultLocale]; ok {
+		return v
+	}
+	for _, locale := range c.Locales() {
+		if v, ok := d.Display[locale]; ok {
+			return v
+		}
+	}
+	return d.Message
+}
+
+// sorted 返回按错误码升序排列的定义
+func (c *Catalog) sorted() []Definition {
+	defs := make([]Definition, len(c.Errors))
+	copy(defs, c.Errors)
+	sort.SliceStable(defs, func(i, j int) bool {
+		return defs[i].Code < defs[j].Code
+	})
+	return defs
+}
+
+var goTemplate = template.Must(template.New("go").Parse(`// Code generated by kerrgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import "github.com/kearth/klib/kerr"
+
+var (
+{{- range .Errors}}
+	// {{.Name}} {{.Message}}
+	{{.Name}} = kerr.MustRegister(kerr.New({{.Code}}, {{printf "%q" .Message}}).WithDisplay({{printf "%q" .Display}}){{range .Options}}, {{.}}{{end}})
+{{- end}}
+)
+`))
+
+// RenderGo 生成 Go 错误码声明，每个错误码通过 kerr.MustRegister 注册
+func (c *Catalog) RenderGo() ([]byte, error) {
+	type goError struct {
+		Name    string
+		Code    int
+		Message string
+		Display string
+		Options []string
+	}
+	data := struct {
+		Package string
+		Errors  []goError
+	}{Package: c.Package}
+
+	for _, d := range c.sorted() {
+		e := goError{Name: d.Name, Code: d.Code, Message: d.Message, Display: c.display(d)}
+		if d.HTTPStatus != 0 {
+			e.Options = append(e.Options, fmt.Sprintf("kerr.WithHTTPStatus(%d)", d.HTTPStatus))
+		}
+		if d.Retryable != nil {
+			e.Options = append(e.Options, fmt.Sprintf("kerr.WithRetryable(%t)", *d.Retryable))
+		}
+		data.Errors = append(data.Errors, e)
+	}
+
+	var buf bytes.Buffer
+	if err := goTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// RenderMarkdown 生成错误码文档表格
+func (c *Catalog) RenderMarkdown() []byte {
+	locales := c.Locales()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s 错误码\n\n", c.Package)
+	if c.Range != nil {
+		fmt.Fprintf(&b, "错误码区间：%d - %d\n\n", c.Range.Min, c.Range.Max)
+	}
+
+	header := []string{"名称", "错误码", "错误信息"}
+	for _, locale := range locales {
+		header = append(header, fmt.Sprintf("显示信息（%s）", locale))
+	}
+	header = append(header, "HTTP 状态码", "可重试")
+	writeRow(&b, header)
+	sep := make([]string, len(header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	writeRow(&b, sep)
+
+	for _, d := range c.sorted() {
+		row := []string{d.Name, strconv.Itoa(d.Code), d.Message}
+		for _, locale := range locales {
+			row = append(row, d.Display[locale])
+		}
+		status := "-"
+		if d.HTTPStatus != 0 {
+			status = strconv.Itoa(d.HTTPStatus)
+		}
+		retryable := "-"
+		if d.Retryable != nil {
+			retryable = strconv.FormatBool(*d.Retryable)
+		}
+		writeRow(&b, append(row, status, retryable))
+	}
+	return []byte(b.String())
+}
+
+// writeRow 写入一行 markdown 表格，转义单元格中的竖线
+func writeRow(b *strings.Builder, cells []string) {
+	b.WriteString("|")
+	for _, cell := range cells {
+		b.WriteString(" ")
+		b.WriteString(strings.ReplaceAll(cell, "|", `\|`))
+		b.WriteString(" |")
+	}
+	b.WriteString("\n")
+}
+
+// RenderJSON 生成供前端使用的 JSON 错误码目录
+func (c *Catalog) RenderJSON() ([]byte, error) {
+	out := *c
+	out.Errors = c.sorted()
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// RenderLocales 按语言生成显示信息目录，可直接由 kerr.LoadCatalogDir 加载
+// 返回 语言 -> JSON 内容，JSON 的键为错误码
+func (c *Catalog) RenderLocales() (map[string][]byte, error) {
+	out := make(map[string][]byte)
+	for _, locale := range c.Locales() {
+		messages := make(map[string]string)
+		for _, d := range c.Errors {
+			if msg, ok := d.Display[locale]; ok {
+				messages[strconv.Itoa(d.Code)] = msg
+			}
+		}
+		data, err := json.MarshalIndent(messages, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		out[locale] = data
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+// kerrgen 根据 YAML/TOML 错误码定义文件生成 Go 错误码声明、markdown 文档、JSON 目录，
+// 以及可由 kerr.LoadCatalogDir 加载的多语言显示信息目录。
+//
+// 用法：
+//
+//	kerrgen -in errors.yaml -go errors_gen.go -md errors.md -json errors.json -i18n i18n
+//
+// 定义文件示例（YAML）：
+//
+//	package: orderr
+//	default_locale: zh
+//	range: {min: 60000, max: 69999}
+//	errors:
+//	  - name: OrderNotFound
+//	    code: 60001
+//	    message: order not found
+//	    display: {zh: 订单不存在, en: Order not found}
+//	    http_status: 404
+//	    retryable: false
+//
+// 错误码重复、名称重复或错误码超出声明区间时，不生成任何文件并以非零状态退出。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	in := flag.String("in", "", "错误码定义文件（.yaml/.yml/.toml）")
+	goOut := flag.String("go", "", "Go 代码输出路径")
+	mdOut := flag.String("md", "", "markdown 文档输出路径")
+	jsonOut := flag.String("json", "", "JSON 目录输出路径")
+	i18nOut := flag.String("i18n", "", "多语言显示信息目录的输出目录，每种语言一个 <locale>.json")
+	flag.Parse()
+
+	if err := run(*in, *goOut, *mdOut, *jsonOut, *i18nOut); err != nil {
+		fmt.Fprintln(os.Stderr, "kerrgen:", err)
+		os.Exit(1)
+	}
+}
+
+// run 读取并校验定义文件，按需生成各类输出
+func run(in, goOut, mdOut, jsonOut, i18nOut string) error {
+	if in == "" {
+		return fmt.Errorf("missing -in definition file")
+	}
+	if goOut == "" && mdOut == "" && jsonOut == "" && i18nOut == "" {
+		return fmt.Errorf("nothing to generate, set at least one of -go, -md, -json, -i18n")
+	}
+
+	c, err := Load(in)
+	if err != nil {
+		return err
+	}
+	if errs := c.Validate(); len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, in+":", e)
+		}
+		return fmt.Errorf("%d invalid definition(s) in %s", len(errs), in)
+	}
+
+	outputs := make(map[string][]byte)
+	if goOut != "" {
+		if outputs[goOut], err = c.RenderGo(); err != nil {
+			return err
+		}
+	}
+	if mdOut != "" {
+		outputs[mdOut] = c.RenderMarkdown()
+	}
+	if jsonOut != "" {
+		if outputs[jsonOut], err = c.RenderJSON(); err != nil {
+			return err
+		}
+	}
+	if i18nOut != "" {
+		locales, err := c.RenderLocales()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(i18nOut, 0o755); err != nil {
+			return err
+		}
+		for locale, data := range locales {
+			outputs[filepath.Join(i18nOut, locale+".json")] = data
+		}
+	}
+	for path, data := range outputs {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoad 测试 YAML 和 TOML 定义文件解析
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{"testdata/errors.yaml", "testdata/errors.toml"} {
+		c, err := Load(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, "orderr", c.Package)
+		assert.Equal(t, &CodeRange{Min: 60000, Max: 69999}, c.Range)
+		assert.Empty(t, c.Validate(), path)
+
+		var found bool
+		for _, d := range c.Errors {
+			if d.Name == "OrderNotFound" {
+				found = true
+				assert.Equal(t, 60001, d.Code)
+				assert.Equal(t, "订单不存在", d.Display["zh"])
+				assert.Equal(t, "Order not found", d.Display["en"])
+				assert.Equal(t, 404, d.HTTPStatus)
+				require.NotNil(t, d.Retryable)
+				assert.False(t, *d.Retryable)
+			}
+		}
+		assert.True(t, found, path)
+	}
+
+	_, err := Parse([]byte("{}"), ".json")
+	assert.Error(t, err)
+}
+
+// TestValidate 测试重复错误码和超出区间的错误码
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	c, err := Load("testdata/invalid.yaml")
+	require.NoError(t, err)
+
+	errs := c.Validate()
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "OrderMissing(60001): duplicate code, already used by OrderNotFound")
+	assert.Contains(t, errs[1].Error(), "Legacy(30001): code out of declared range [60000, 69999]")
+
+	c = &Catalog{Package: "bad-name", Errors: []Definition{{Name: "lower", Code: 1, HTTPStatus: 999}}}
+	assert.Len(t, c.Validate(), 4)
+}
+
+// TestRenderGo 测试生成的 Go 代码
+func TestRenderGo(t *testing.T) {
+	t.Parallel()
+
+	c, err := Load("testdata/errors.yaml")
+	require.NoError(t, err)
+
+	src, err := c.RenderGo()
+	require.NoError(t, err)
+	code := string(src)
+
+	assert.True(t, strings.HasPrefix(code, "// Code generated by kerrgen. DO NOT EDIT."))
+	assert.Contains(t, code, "package orderr")
+	assert.Contains(t, code, `OrderNotFound = kerr.MustRegister(kerr.New(60001, "order not found").WithDisplay("订单不存在"), kerr.WithHTTPStatus(404), kerr.WithRetryable(false))`)
+	assert.Contains(t, code, `PaymentGatewayBusy = kerr.MustRegister(kerr.New(60003, "payment gateway busy").WithDisplay("payment gateway busy"), kerr.WithRetryable(true))`)
+	// 按错误码排序输出
+	assert.Less(t, strings.Index(code, "OrderNotFound ="), strings.Index(code, "OrderPaid ="))
+}
+
+// TestRenderMarkdownJSON 测试 markdown 文档和 JSON 目录
+func TestRenderMarkdownJSON(t *testing.T) {
+	t.Parallel()
+
+	c, err := Load("testdata/errors.yaml")
+	require.NoError(t, err)
+
+	md := string(c.RenderMarkdown())
+	assert.Contains(t, md, "| 名称 | 错误码 | 错误信息 | 显示信息（zh） | 显示信息（en） | 显示信息（ja） | HTTP 状态码 | 可重试 |")
+	assert.Contains(t, md, "| OrderNotFound | 60001 | order not found | 订单不存在 | Order not found |  | 404 | false |")
+	assert.Contains(t, md, "| PaymentGatewayBusy | 60003 | payment gateway busy |  |  |  | - | true |")
+
+	data, err := c.RenderJSON()
+	require.NoError(t, err)
+	var out Catalog
+	require.NoError(t, json.Unmarshal(data, &out))
+	require.Len(t, out.Errors, 3)
+	assert.Equal(t, "OrderNotFound", out.Errors[0].Name)
+	assert.Equal(t, "注文は支払い済みです", out.Errors[1].Display["ja"])
+
+	locales, err := c.RenderLocales()
+	require.NoError(t, err)
+	require.Len(t, locales, 3)
+	var en map[string]string
+	require.NoError(t, json.Unmarshal(locales["en"], &en))
+	assert.Equal(t, map[string]string{"60001": "Order not found", "60002": "Order already paid"}, en)
+}
+
+// TestRun 测试命令行入口：校验失败时不生成文件
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	goOut := filepath.Join(dir, "errors_gen.go")
+	mdOut := filepath.Join(dir, "errors.md")
+	jsonOut := filepath.Join(dir, "errors.json")
+	i18nOut := filepath.Join(dir, "i18n")
+
+	require.NoError(t, run("testdata/errors.toml", goOut, mdOut, jsonOut, i18nOut))
+	for _, path := range []string{goOut, mdOut, jsonOut, filepath.Join(i18nOut, "zh.json"), filepath.Join(i18nOut, "en.json")} {
+		_, err := os.Stat(path)
+		assert.NoError(t, err, path)
+	}
+
+	invalidOut := filepath.Join(dir, "invalid_gen.go")
+	assert.Error(t, run("testdata/invalid.yaml", invalidOut, "", "", ""))
+	_, err := os.Stat(invalidOut)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Error(t, run("", goOut, "", "", ""))
+	assert.Error(t, run("testdata/errors.yaml", "", "", "", ""))
+}
@@ -0,0 +1,23 @@
+// kerrlint 检查 kerr 的常见误用：
+//
+//   - kerrcompare：使用 == / != 或 switch 与哨兵错误比较，应使用 errors.Is
+//   - kerrdiscard：丢弃 WithStack、WithField、Wrap 等方法返回的新错误
+//   - kerrcode：kerr.New 复用已注册的错误码但错误信息不同
+//
+// 用法：
+//
+//	kerrlint ./...
+//	kerrlint -kerrcompare ./...    # 仅运行指定的分析器
+//	kerrlint -fix ./...            # 应用修复建议，将比较改写为 errors.Is
+//
+// 有意为之的用法在所在行或上一行添加 //kerrlint:ignore [分析器名称] 说明 抑制诊断。
+package main
+
+import (
+	"github.com/kearth/klib/kerr/kerrlint"
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() {
+	multichecker.Main(kerrlint.Analyzers...)
+}
@@ -5,6 +5,10 @@
 // 核心特性：
 // - 完全兼容标准 context 接口，可无缝替换原生 context
 // - 内置 TraceID 用于分布式追踪，支持从父上下文继承
+// - 支持 W3C Trace Context（traceparent/tracestate）的提取与注入
+// - 支持 StartSpan 开启子 span，结束的 span 交给可插拔的 SpanProcessor
+// - 与 OpenTelemetry 互通：沿用活动 OTel span 的追踪信息，元数据镜像到 OTel baggage
+// - 提供 net/http 服务端中间件和客户端 Transport，在 HTTP 头中传递追踪信息和元数据
 // - 提供线程安全的 Set/Get 方法管理元数据
 // - 支持 WithCancel/WithTimeout 等衍生上下文创建
 package kctx
@@ -13,8 +17,6 @@ import (
 	"context"
 	"sync"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 const (
@@ -38,13 +40,17 @@ type (
 		Context() context.Context
 		SetContext(ctx context.Context)
 		TraceID() string
+		SpanContext() SpanContext
 	}
 
 	// 上下文实现
 	kCtx struct {
 		ctx     context.Context   // 底层标准context
 		traceID string            // 不可变TraceID（无需锁保护）
+		span    SpanContext       // 不可变追踪信息（无需锁保护）
+		active  *activeSpan       // StartSpan 开启的进行中 span，与衍生上下文共享
 		meta    map[string]string // 字符串元数据映射
+		bag     context.Context   // 镜像元数据的 OTel baggage，优先于底层context查找
 		mu      sync.RWMutex      // 保护 metamap 和 context 的并发访问
 	}
 )
@@ -56,11 +62,35 @@ func New(parent ...context.Context) Context {
 	if len(parent) > 0 && parent[0] != nil {
 		baseCtx = parent[0]
 	}
-	// 继承或生成TraceID：优先从父上下文获取，其次生成新UUID
-	traceID := uuid.NewString()
+	// 继承或生成TraceID：优先从父上下文获取，其次生成新的 W3C 追踪 ID
+	var traceID string
 	if parentTraceID, ok := baseCtx.Value(TraceIDKey).(string); ok && parentTraceID != "" {
 		traceID = parentTraceID
 	}
+	// 继承追踪信息；父上下文只有 TraceID 时，32 位十六进制或 UUID 格式的 TraceID 沿用为追踪 ID，
+	// 其他格式的 TraceID 原样保留，追踪 ID 重新生成
+	span, _ := baseCtx.Value(SpanContextKey).(SpanContext)
+	var active *activeSpan
+	if sc, ok := activeOTelSpan(baseCtx); ok {
+		// 父上下文中有活动的 OTel span 时沿用其追踪信息，TraceID 与其不一致时重新生成
+		span = sc
+		if id, ok := parseTraceID(traceID); !ok || id != span.TraceID {
+			traceID = ""
+		}
+	} else if span.TraceID.IsValid() {
+		active = activeSpanOf(baseCtx)
+	} else {
+		// 新的追踪没有 span，StartSpan 开启的第一个 span 为根 span
+		span = SpanContext{Flags: FlagSampled}
+		if id, ok := parseTraceID(traceID); ok {
+			span.TraceID = id
+		} else {
+			span.TraceID = newTraceID()
+		}
+	}
+	if traceID == "" {
+		traceID = span.TraceID.String()
+	}
 
 	meta := make(map[string]string)
 	if parentMeta, ok := baseCtx.Value(MetaMapKey).(map[string]string); ok {
@@ -70,6 +100,8 @@ func New(parent ...context.Context) Context {
 	return &kCtx{
 		ctx:     baseCtx,
 		traceID: traceID,
+		span:    span,
+		active:  active,
 		meta:    meta,
 	}
 }
@@ -80,6 +112,7 @@ func (k *kCtx) Get(key string) string {
 	return k.meta[key]
 }
 
+// Set 设置元数据，同时镜像到 OTel baggage；无法写入 baggage 的键值（如非 UTF-8）只写入元数据
 func (k *kCtx) Set(key string, val string) {
 	// 空键直接忽略，避免无效数据
 	if key == "" {
@@ -101,6 +134,7 @@ func (k *kCtx) Set(key string, val string) {
 	}
 	newMeta[key] = val
 	k.meta = newMeta
+	k.bag = mirrorBaggage(k.ctx, newMeta)
 }
 
 // Values 返回元数据副本，彻底杜绝外部修改内部状态
@@ -127,6 +161,12 @@ func (k *kCtx) TraceID() string {
 	return k.traceID
 }
 
+// SpanContext 返回不可变的追踪信息（无需锁，初始化后不再修改）
+// New 创建的上下文没有 span，SpanID 为空；Extract 得到的上下文为上游 span，Remote 为 true
+func (k *kCtx) SpanContext() SpanContext {
+	return k.span
+}
+
 // SetContext 替换底层标准context，增加nil校验
 func (k *kCtx) SetContext(ctx context.Context) {
 	if ctx == nil {
@@ -136,6 +176,7 @@ func (k *kCtx) SetContext(ctx context.Context) {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 	k.ctx = ctx
+	k.bag = mirrorBaggage(ctx, k.meta)
 }
 
 // --------------- 实现context.Context接口 ---------------
@@ -164,14 +205,27 @@ func (k *kCtx) Value(key any) any {
 		switch keyStr {
 		case TraceIDKey:
 			return k.traceID
+		case SpanContextKey:
+			return k.span
 		case MetaMapKey:
 			return k.Values() // 返回副本，安全无副作用
 		}
 	}
+	switch key.(type) {
+	case activeSpanKey:
+		return k.active
+	case ctxKey:
+		return k
+	}
 
 	// 其他键从底层context获取，加锁保证并发安全
 	k.mu.RLock()
 	defer k.mu.RUnlock()
+	if k.bag != nil {
+		if v := k.bag.Value(key); v != nil {
+			return v
+		}
+	}
 	return k.ctx.Value(key)
 }
 
@@ -190,7 +244,10 @@ func copyCtx(src *kCtx) *kCtx {
 	return &kCtx{
 		ctx:     src.ctx,     // 复用底层context（引用类型，符合context设计理念）
 		traceID: src.traceID, // TraceID不可变，直接复用
+		span:    src.span,    // 追踪信息不可变，直接复用
+		active:  src.active,  // 进行中的 span 与衍生上下文共享
 		meta:    newMeta,     // 元数据深拷贝，隔离变更
+		bag:     src.bag,     // baggage 不可变，直接复用
 	}
 }
 
@@ -0,0 +1,126 @@
+package kctx
+
+import (
+	"context"
+	"net/http"
+)
+
+// DefaultTraceIDHeader 默认写入 TraceID 的 HTTP 头
+const DefaultTraceIDHeader = "X-Trace-Id"
+
+// maxTraceIDHeaderLen 从请求头接受的 TraceID 最大长度
+const maxTraceIDHeaderLen = 128
+
+// HTTPOptions HTTPMiddleware 和 Transport 的配置
+type HTTPOptions struct {
+	// TraceIDHeader 传递 TraceID 的头，为空时使用 DefaultTraceIDHeader。
+	// 服务端在请求没有有效的 traceparent 时从该头读取 TraceID，并在响应中写入；客户端在请求中写入
+	TraceIDHeader string
+	// MetaHeaders 元数据白名单，元数据键 -> HTTP 头；只有白名单内的元数据会在请求头和上下文之间传递
+	MetaHeaders map[string]string
+}
+
+// traceIDHeader 返回传递 TraceID 的头
+func (o HTTPOptions) traceIDHeader() string {
+	if o.TraceIDHeader == "" {
+		return DefaultTraceIDHeader
+	}
+	return o.TraceIDHeader
+}
+
+// HTTPMiddleware 返回 net/http 服务端中间件：从请求头提取追踪信息和白名单内的元数据创建 kctx，
+// 作为请求的上下文传给下游 handler，并在响应头中写入 TraceID
+//
+//	mux := http.NewServeMux()
+//	http.ListenAndServe(":8080", kctx.HTTPMiddleware(kctx.HTTPOptions{
+//		MetaHeaders: map[string]string{"tenant": "X-Tenant"},
+//	})(mux))
+func HTTPMiddleware(opts HTTPOptions) func(http.Handler) http.Handler {
+	header := opts.traceIDHeader()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent := r.Context()
+			if _, err := ParseTraceParent(r.Header.Get(TraceParentHeader)); err != nil {
+				if traceID := r.Header.Get(header); validTraceIDHeader(traceID) {
+					parent = context.WithValue(parent, TraceIDKey, traceID)
+				}
+			}
+			ctx := Extract(HeaderCarrier(r.Header), parent)
+			for key, h := range opts.MetaHeaders {
+				if val := r.Header.Get(h); val != "" {
+					ctx.Set(key, val)
+				}
+			}
+
+			w.Header().Set(header, ctx.TraceID())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// validTraceIDHeader 校验请求头中的 TraceID：非空、不超过最大长度，只含字母、数字和 "-_."
+func validTraceIDHeader(s string) bool {
+	if s == "" || len(s) > maxTraceIDHeaderLen {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// transport 将上下文的追踪信息和元数据写入请求头的 http.RoundTripper
+type transport struct {
+	base http.RoundTripper
+	opts HTTPOptions
+}
+
+// Transport 返回 net/http 客户端 Transport：将请求上下文的 traceparent、tracestate、TraceID
+// 和白名单内的元数据写入请求头后交给 base 发送；base 为 nil 时使用 http.DefaultTransport，
+// 不传 opts 时使用默认配置
+//
+//	client := &http.Client{Transport: kctx.Transport(nil, kctx.HTTPOptions{
+//		MetaHeaders: map[string]string{"tenant": "X-Tenant"},
+//	})}
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+func Transport(base http.RoundTripper, opts ...HTTPOptions) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &transport{base: base}
+	if len(opts) > 0 {
+		t.opts = opts[0]
+	}
+	return t
+}
+
+// RoundTrip 实现 http.RoundTripper 接口，不修改调用方的请求
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	out := req.Clone(ctx)
+	Inject(ctx, HeaderCarrier(out.Header))
+	if traceID, ok := ctx.Value(TraceIDKey).(string); ok && traceID != "" {
+		out.Header.Set(t.opts.traceIDHeader(), traceID)
+	}
+	if meta, ok := ctx.Value(MetaMapKey).(map[string]string); ok {
+		for key, h := range t.opts.MetaHeaders {
+			if val, ok := meta[key]; ok && val != "" {
+				out.Header.Set(h, val)
+			}
+		}
+	}
+	return t.base.RoundTrip(out)
+}
+
+// CloseIdleConnections 底层 Transport 支持时关闭空闲连接
+func (t *transport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,158 @@
+package kctx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// httpSeen 下游 handler 看到的上下文信息
+type httpSeen struct {
+	traceID string
+	span    SpanContext
+	meta    map[string]string
+	header  http.Header
+}
+
+// newHTTPServer 创建挂载中间件的测试服务，返回服务和下游 handler 看到的上下文信息
+func newHTTPServer(t *testing.T, opts HTTPOptions) (*httptest.Server, *httpSeen) {
+	t.Helper()
+	seen := &httpSeen{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := r.Context().(Context)
+		if !assert.True(t, ok, "请求上下文应为 kctx") {
+			return
+		}
+		seen.traceID = ctx.TraceID()
+		seen.span = ctx.SpanContext()
+		seen.meta = ctx.Values()
+		seen.header = r.Header.Clone()
+	})
+	srv := httptest.NewServer(HTTPMiddleware(opts)(handler))
+	t.Cleanup(srv.Close)
+	return srv, seen
+}
+
+// TestHTTPEndToEnd 测试客户端 Transport 到服务端中间件的端到端传递
+func TestHTTPEndToEnd(t *testing.T) {
+	t.Parallel()
+
+	opts := HTTPOptions{MetaHeaders: map[string]string{"tenant": "X-Tenant", "user_id": "X-User-Id"}}
+	srv, seen := newHTTPServer(t, opts)
+	client := &http.Client{Transport: Transport(nil, opts)}
+
+	ctx := New()
+	ctx.Set("tenant", "acme")
+	ctx.Set("secret", "not-allowlisted")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, ctx.TraceID(), seen.traceID)
+	assert.Equal(t, ctx.SpanContext().TraceID, seen.span.TraceID)
+	assert.False(t, seen.span.SpanID.IsValid(), "调用方没有 span 时不传递 traceparent")
+	assert.Equal(t, map[string]string{"tenant": "acme"}, seen.meta)
+	assert.Empty(t, seen.header.Get(TraceParentHeader))
+	assert.Equal(t, ctx.TraceID(), seen.header.Get(DefaultTraceIDHeader))
+	assert.Empty(t, seen.header.Get("X-User-Id"))
+	assert.Equal(t, ctx.TraceID(), resp.Header.Get(DefaultTraceIDHeader))
+
+	// 不修改调用方的请求
+	assert.Empty(t, req.Header)
+
+	// 子 span 作为下游的父 span
+	spanCtx, end := StartSpan(ctx, "call")
+	defer end()
+	req, err = http.NewRequestWithContext(spanCtx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Contains(t, seen.header.Get(TraceParentHeader), spanCtx.SpanContext().SpanID.String())
+	assert.Equal(t, spanCtx.SpanContext().SpanID, seen.span.SpanID, "服务端以上游 span 作为上下文的 span")
+	assert.True(t, seen.span.Remote)
+}
+
+// TestHTTPMiddlewareTraceIDHeader 测试服务端从自定义头读取 TraceID
+func TestHTTPMiddlewareTraceIDHeader(t *testing.T) {
+	t.Parallel()
+
+	srv, seen := newHTTPServer(t, HTTPOptions{TraceIDHeader: "X-Request-Id"})
+	get := func(header map[string]string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	// 没有 traceparent 时沿用请求头中的 TraceID
+	resp := get(map[string]string{"X-Request-Id": testLegacyID})
+	assert.Equal(t, testLegacyID, seen.traceID)
+	assert.Equal(t, "550e8400e29b41d4a716446655440000", seen.span.TraceID.String())
+	assert.Equal(t, testLegacyID, resp.Header.Get("X-Request-Id"))
+	assert.Empty(t, resp.Header.Get(DefaultTraceIDHeader))
+
+	resp = get(map[string]string{"X-Request-Id": "order-svc.42"})
+	assert.Equal(t, "order-svc.42", seen.traceID)
+	assert.Equal(t, "order-svc.42", resp.Header.Get("X-Request-Id"))
+
+	// traceparent 优先于 TraceID 头
+	get(map[string]string{"X-Request-Id": testLegacyID, TraceParentHeader: testTraceParent})
+	assert.Equal(t, testTraceID, seen.traceID)
+
+	// 非法的 TraceID 被忽略，生成新的 TraceID
+	resp = get(map[string]string{"X-Request-Id": "bad trace id;<x>"})
+	assert.Len(t, seen.traceID, 32)
+	assert.Equal(t, seen.traceID, resp.Header.Get("X-Request-Id"))
+}
+
+// TestTransport 测试客户端 Transport 写入的请求头
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	var got *http.Request
+	base := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody, Request: r}, nil
+	})
+	rt := Transport(base, HTTPOptions{TraceIDHeader: "X-Request-Id", MetaHeaders: map[string]string{"tenant": "X-Tenant"}})
+
+	ctx := Extract(MapCarrier{TraceParentHeader: testTraceParent, TraceStateHeader: "congo=t61rcWkgMzE"})
+	ctx.Set("tenant", "acme")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.invalid", nil)
+	require.NoError(t, err)
+	resp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	assert.Equal(t, ctx.SpanContext().TraceParent(), got.Header.Get(TraceParentHeader))
+	assert.Equal(t, "congo=t61rcWkgMzE", got.Header.Get(TraceStateHeader))
+	assert.Equal(t, testTraceID, got.Header.Get("X-Request-Id"))
+	assert.Equal(t, "acme", got.Header.Get("X-Tenant"))
+
+	// 标准上下文不携带追踪信息时不写入
+	req, err = http.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.invalid", nil)
+	require.NoError(t, err)
+	_, err = Transport(base).RoundTrip(req)
+	require.NoError(t, err)
+	assert.Empty(t, got.Header)
+}
+
+// roundTripFunc 函数形式的 http.RoundTripper
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+// RoundTrip 实现 http.RoundTripper 接口
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
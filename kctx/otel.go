@@ -0,0 +1,88 @@
+package kctx
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ctxKey 用于在上下文中获取最近的 kctx 实例的键
+type ctxKey struct{}
+
+// WithOTel 返回以 parent 的追踪信息作为 OTel 父 span 的上下文，用于开启 OTel span：
+// 底层 context 已携带相同的 OTel span 时不做替换，否则以 parent 的 SpanContext 作为远端父 span
+//
+//	ctx := kctx.Extract(kctx.HeaderCarrier(r.Header), r.Context())
+//	otelCtx, span := tracer.Start(kctx.WithOTel(ctx), "handle")
+func WithOTel(parent Context) Context {
+	k, ok := parent.(*kCtx)
+	if ok {
+		k = copyCtx(k)
+	} else {
+		k = New(parent).(*kCtx)
+	}
+
+	base := k.Context()
+	current := trace.SpanContextFromContext(base)
+	if current.TraceID() == trace.TraceID(k.span.TraceID) && current.SpanID() == trace.SpanID(k.span.SpanID) {
+		return k
+	}
+	k.SetContext(trace.ContextWithRemoteSpanContext(base, toOTel(k.span)))
+	return k
+}
+
+// activeOTelSpan 返回 ctx 中活动的 OTel span 的追踪信息；
+// OTel span 位于最近的 kctx 之下时（kctx 由其创建或已通过 WithOTel 桥接），以 kctx 的追踪信息为准
+func activeOTelSpan(ctx context.Context) (SpanContext, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	if k, ok := ctx.Value(ctxKey{}).(*kCtx); ok && trace.SpanContextFromContext(k.Context()).Equal(sc) {
+		return SpanContext{}, false
+	}
+	return fromOTel(sc), true
+}
+
+// toOTel 转换为 OTel 的 SpanContext
+func toOTel(sc SpanContext) trace.SpanContext {
+	state, _ := trace.ParseTraceState(sc.TraceState)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID(sc.TraceID),
+		SpanID:     trace.SpanID(sc.SpanID),
+		TraceFlags: trace.TraceFlags(sc.Flags),
+		TraceState: state,
+		Remote:     sc.Remote,
+	})
+}
+
+// fromOTel 从 OTel 的 SpanContext 转换
+func fromOTel(sc trace.SpanContext) SpanContext {
+	return SpanContext{
+		TraceID:    TraceID(sc.TraceID()),
+		SpanID:     SpanID(sc.SpanID()),
+		Flags:      TraceFlags(sc.TraceFlags()),
+		TraceState: sc.TraceState().String(),
+		Remote:     sc.IsRemote(),
+	}
+}
+
+// mirrorBaggage 将元数据合并到 base 的 OTel baggage 中，返回只携带合并结果的上下文；
+// 非 UTF-8 的键值或超出 baggage 大小限制的成员被跳过，没有元数据时返回 nil
+func mirrorBaggage(base context.Context, meta map[string]string) context.Context {
+	if len(meta) == 0 {
+		return nil
+	}
+	b := baggage.FromContext(base)
+	for key, val := range meta {
+		m, err := baggage.NewMemberRaw(key, val)
+		if err != nil {
+			continue
+		}
+		if merged, err := b.SetMember(m); err == nil {
+			b = merged
+		}
+	}
+	return baggage.ContextWithBaggage(context.Background(), b)
+}
@@ -0,0 +1,125 @@
+package kctx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestTracer 创建导出到内存的 OTel tracer
+func newTestTracer(t *testing.T) (trace.Tracer, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return tp.Tracer("kctx-test"), exporter
+}
+
+// TestNewAdoptsOTelSpan 测试 New 沿用活动 OTel span 的追踪信息
+func TestNewAdoptsOTelSpan(t *testing.T) {
+	t.Parallel()
+	tracer, _ := newTestTracer(t)
+
+	otelCtx, span := tracer.Start(context.Background(), "server")
+	defer span.End()
+	osc := span.SpanContext()
+
+	ctx := New(otelCtx)
+	assert.Equal(t, osc.TraceID().String(), ctx.TraceID())
+	assert.Equal(t, osc.TraceID().String(), ctx.SpanContext().TraceID.String())
+	assert.Equal(t, osc.SpanID().String(), ctx.SpanContext().SpanID.String())
+	assert.True(t, ctx.SpanContext().Flags.IsSampled())
+
+	// OTel span 优先于外层 kctx 的 TraceID
+	outer := New()
+	inner, innerSpan := tracer.Start(outer, "inner")
+	defer innerSpan.End()
+	assert.Equal(t, innerSpan.SpanContext().TraceID().String(), New(inner).TraceID())
+
+	// OTel span 位于 kctx 之下时以 kctx 的 span 为准
+	child, end := StartSpan(ctx, "local")
+	defer end()
+	assert.Equal(t, child.SpanContext(), New(child).SpanContext())
+	assert.Equal(t, child.SpanContext(), New(context.WithValue(child, "k", "v")).SpanContext())
+
+	// 没有活动的 OTel span 时不受影响
+	plain := New()
+	assert.Equal(t, plain.SpanContext(), New(plain).SpanContext())
+}
+
+// TestWithOTel 测试以 kctx 的追踪信息开启 OTel span
+func TestWithOTel(t *testing.T) {
+	t.Parallel()
+	tracer, exporter := newTestTracer(t)
+
+	ctx := Extract(MapCarrier{TraceParentHeader: testTraceParent})
+	ctx.Set("tenant", "acme")
+	seeded := WithOTel(ctx)
+	assert.Equal(t, ctx.SpanContext(), seeded.SpanContext())
+	assert.Equal(t, "acme", seeded.Get("tenant"))
+
+	otelCtx, span := tracer.Start(seeded, "handle")
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, testTraceID, spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, ctx.SpanContext().SpanID.String(), spans[0].Parent.SpanID().String())
+	assert.True(t, spans[0].Parent.IsRemote())
+
+	// OTel span 的上下文回到 kctx 后沿用该 span
+	back := New(otelCtx)
+	assert.Equal(t, testTraceID, back.TraceID())
+	assert.Equal(t, spans[0].SpanContext.SpanID().String(), back.SpanContext().SpanID.String())
+	assert.Equal(t, "acme", back.Get("tenant"))
+
+	// 已携带相同 OTel span 时不做替换
+	assert.Equal(t, span.SpanContext(), trace.SpanContextFromContext(WithOTel(back)))
+}
+
+// TestBaggageMirror 测试元数据镜像到 OTel baggage
+func TestBaggageMirror(t *testing.T) {
+	t.Parallel()
+
+	base, err := baggage.New(mustMember(t, "region", "cn-north"))
+	require.NoError(t, err)
+	ctx := New(baggage.ContextWithBaggage(context.Background(), base))
+	ctx.Set("tenant", "acme corp")
+	ctx.Set("bad\xffkey", "skipped")
+
+	b := baggage.FromContext(ctx)
+	assert.Equal(t, "acme corp", b.Member("tenant").Value())
+	assert.Equal(t, "cn-north", b.Member("region").Value())
+	assert.Equal(t, 2, b.Len(), "非 UTF-8 的键不写入 baggage")
+	assert.Equal(t, "skipped", ctx.Get("bad\xffkey"))
+
+	// 衍生上下文和更新后的元数据同步到 baggage
+	child, cancel := WithCancel(ctx)
+	defer cancel()
+	child.Set("tenant", "globex")
+	assert.Equal(t, "globex", baggage.FromContext(child).Member("tenant").Value())
+	assert.Equal(t, "cn-north", baggage.FromContext(child).Member("region").Value())
+	assert.Equal(t, "acme corp", baggage.FromContext(ctx).Member("tenant").Value())
+
+	// 替换底层 context 后重新合并
+	ctx.SetContext(context.Background())
+	assert.Equal(t, "acme corp", baggage.FromContext(ctx).Member("tenant").Value())
+	assert.Empty(t, baggage.FromContext(ctx).Member("region").Value())
+
+	// 没有元数据时不影响底层 context 的 baggage
+	assert.Equal(t, "cn-north", baggage.FromContext(New(baggage.ContextWithBaggage(context.Background(), base))).Member("region").Value())
+}
+
+// mustMember 创建 baggage 成员
+func mustMember(t *testing.T, key, val string) baggage.Member {
+	t.Helper()
+	m, err := baggage.NewMemberRaw(key, val)
+	require.NoError(t, err)
+	return m
+}
@@ -0,0 +1,155 @@
+package kctx
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TraceParentHeader W3C Trace Context 的 traceparent 键
+	TraceParentHeader = "traceparent"
+	// TraceStateHeader W3C Trace Context 的 tracestate 键
+	TraceStateHeader = "tracestate"
+)
+
+// TextMapCarrier 传播追踪信息的键值载体，如 HTTP 头、消息队列属性
+type TextMapCarrier interface {
+	Get(key string) string
+	Set(key string, value string)
+	Keys() []string
+}
+
+// HeaderCarrier 将 http.Header 适配为 TextMapCarrier，键不区分大小写
+type HeaderCarrier http.Header
+
+// Get 返回键对应的第一个值
+func (h HeaderCarrier) Get(key string) string {
+	return http.Header(h).Get(key)
+}
+
+// Set 设置键的值，覆盖已有的值
+func (h HeaderCarrier) Set(key string, value string) {
+	http.Header(h).Set(key, value)
+}
+
+// Keys 返回所有键
+func (h HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// MapCarrier 将 map[string]string 适配为 TextMapCarrier，Get 在精确匹配失败时不区分大小写查找
+type MapCarrier map[string]string
+
+// Get 返回键对应的值
+func (m MapCarrier) Get(key string) string {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Set 设置键的值
+func (m MapCarrier) Set(key string, value string) {
+	m[key] = value
+}
+
+// Keys 返回所有键
+func (m MapCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// propagation 传播配置，legacyKey 非空时开启旧版 UUID TraceID 的兼容模式
+var propagation = struct {
+	mu        sync.RWMutex
+	legacyKey string
+}{}
+
+// SetLegacyTraceKey 开启兼容模式：没有有效的 traceparent 时，从 key 读取旧版 UUID 格式的 TraceID，
+// Inject 时也同时写入该键，便于与尚未升级的服务互通；key 为空时关闭兼容模式（默认）
+//
+//	kctx.SetLegacyTraceKey("X-Trace-Id")
+func SetLegacyTraceKey(key string) {
+	propagation.mu.Lock()
+	defer propagation.mu.Unlock()
+	propagation.legacyKey = key
+}
+
+// legacyTraceKey 返回兼容模式的键，未开启时为空
+func legacyTraceKey() string {
+	propagation.mu.RLock()
+	defer propagation.mu.RUnlock()
+	return propagation.legacyKey
+}
+
+// Extract 从载体中提取追踪信息，创建继承上游 TraceID 的上下文
+// 提取到有效的 traceparent 时，上游 span（Remote 为 true）作为上下文的 span，之后 StartSpan 开启的 span 以其为父 span，
+// Inject 原样传递上游的 traceparent；兼容模式下没有有效的 traceparent 时接受旧版 UUID TraceID，
+// 上游没有 span ID，上下文同 New 没有 span；均没有时同 New
+//
+//	ctx := kctx.Extract(kctx.HeaderCarrier(r.Header), r.Context())
+func Extract(carrier TextMapCarrier, parent ...context.Context) Context {
+	k := New(parent...).(*kCtx)
+	if carrier == nil {
+		return k
+	}
+
+	if sc, err := ParseTraceParent(carrier.Get(TraceParentHeader)); err == nil {
+		sc.TraceState = normalizeTraceState(carrier.Get(TraceStateHeader))
+		sc.Remote = true
+		k.traceID = sc.TraceID.String()
+		k.span = sc
+		k.active = nil
+		return k
+	}
+
+	if key := legacyTraceKey(); key != "" {
+		legacy := carrier.Get(key)
+		if u, err := uuid.Parse(legacy); err == nil && len(legacy) == 36 && TraceID(u).IsValid() {
+			k.traceID = strings.ToLower(legacy)
+			k.span = SpanContext{TraceID: TraceID(u), Flags: FlagSampled}
+			k.active = nil
+		}
+	}
+	return k
+}
+
+// Inject 将上下文的追踪信息写入载体：ctx 有 span（StartSpan 开启的 span 或 Extract 得到的上游 span）时写入 traceparent，
+// 以该 span 作为下游的父 span，tracestate 非空时写入 tracestate；New 创建的上下文没有 span，不写入 traceparent，
+// 以免下游引用不存在的父 span，需要时先用 StartSpan 开启调用的 span。兼容模式下同时写入 TraceID
+//
+//	ctx, end := kctx.StartSpan(ctx, "call order-svc")
+//	defer end()
+//	kctx.Inject(ctx, kctx.HeaderCarrier(req.Header))
+func Inject(ctx context.Context, carrier TextMapCarrier) {
+	if ctx == nil || carrier == nil {
+		return
+	}
+	if sc, ok := ctx.Value(SpanContextKey).(SpanContext); ok && sc.IsValid() {
+		carrier.Set(TraceParentHeader, sc.TraceParent())
+		if sc.TraceState != "" {
+			carrier.Set(TraceStateHeader, sc.TraceState)
+		}
+	}
+	if key := legacyTraceKey(); key != "" {
+		if traceID, ok := ctx.Value(TraceIDKey).(string); ok && traceID != "" {
+			carrier.Set(key, traceID)
+		}
+	}
+}
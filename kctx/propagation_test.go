@@ -0,0 +1,179 @@
+package kctx
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testLegacyID    = "550e8400-e29b-41d4-a716-446655440000"
+)
+
+// TestParseTraceParent 测试 traceparent 的解析与生成
+func TestParseTraceParent(t *testing.T) {
+	t.Parallel()
+
+	sc, err := ParseTraceParent(testTraceParent)
+	require.NoError(t, err)
+	assert.Equal(t, testTraceID, sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Flags.IsSampled())
+	assert.Equal(t, testTraceParent, sc.TraceParent())
+
+	// 更高版本忽略扩展字段
+	sc, err = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-ext")
+	require.NoError(t, err)
+	assert.False(t, sc.Flags.IsSampled())
+
+	for _, invalid := range []string{
+		"",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",  // 大写
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",  // 全零追踪 ID
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",  // 全零 span ID
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",  // 非法版本
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-", // 版本 00 不允许扩展
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",
+	} {
+		_, err := ParseTraceParent(invalid)
+		assert.ErrorIs(t, err, ErrInvalidTraceParent, invalid)
+	}
+	assert.Empty(t, SpanContext{}.TraceParent())
+}
+
+// TestExtract 测试从 HTTP 头提取追踪信息
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Set("Traceparent", testTraceParent)
+	header.Set("Tracestate", " congo=t61rcWkgMzE , rojo=00f067aa0ba902b7 ")
+
+	parent, cancel := context.WithCancel(context.Background())
+	ctx := Extract(HeaderCarrier(header), parent)
+	sc := ctx.SpanContext()
+	assert.Equal(t, testTraceID, ctx.TraceID())
+	assert.Equal(t, testTraceID, sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String(), "上游 span 作为上下文的 span")
+	assert.True(t, sc.Remote)
+	assert.True(t, sc.IsValid())
+	assert.True(t, sc.Flags.IsSampled())
+	assert.Equal(t, "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", sc.TraceState)
+	assert.Equal(t, sc, ctx.Value(SpanContextKey))
+
+	// 保留父上下文的取消信号
+	cancel()
+	assert.Error(t, ctx.Err())
+
+	// 非法的 tracestate 被丢弃
+	header.Set("Tracestate", "Bad Key=1")
+	assert.Empty(t, Extract(HeaderCarrier(header)).SpanContext().TraceState)
+
+	// 没有 traceparent 时生成新的 W3C 追踪 ID
+	fresh := Extract(HeaderCarrier(http.Header{}))
+	assert.Len(t, fresh.TraceID(), 32)
+	assert.Equal(t, fresh.TraceID(), fresh.SpanContext().TraceID.String())
+	assert.NotEqual(t, testTraceID, fresh.TraceID())
+}
+
+// TestInject 测试将追踪信息写入载体
+func TestInject(t *testing.T) {
+	t.Parallel()
+
+	in := MapCarrier{"TraceParent": testTraceParent, "tracestate": "congo=t61rcWkgMzE"}
+	ctx := Extract(in)
+
+	out := MapCarrier{}
+	Inject(ctx, out)
+	sc, err := ParseTraceParent(out[TraceParentHeader])
+	require.NoError(t, err)
+	assert.Equal(t, ctx.SpanContext().TraceID, sc.TraceID)
+	assert.Equal(t, ctx.SpanContext().SpanID, sc.SpanID)
+	assert.Equal(t, "congo=t61rcWkgMzE", out[TraceStateHeader])
+
+	// 衍生上下文沿用同一追踪信息
+	child, cancel := WithCancel(ctx)
+	defer cancel()
+	assert.Equal(t, ctx.SpanContext(), child.SpanContext())
+
+	// New 创建的上下文没有 span，不写入 traceparent；开启 span 后以该 span 作为下游的父 span
+	header := http.Header{}
+	root := New()
+	Inject(root, HeaderCarrier(header))
+	assert.Empty(t, header.Get(TraceParentHeader))
+	call, end := StartSpan(root, "call")
+	defer end()
+	Inject(call, HeaderCarrier(header))
+	assert.Equal(t, call.SpanContext().TraceParent(), header.Get(TraceParentHeader))
+	assert.Contains(t, header.Get(TraceParentHeader), root.TraceID())
+	assert.Empty(t, header.Get(TraceStateHeader))
+
+	// 标准上下文不携带追踪信息时不写入
+	empty := MapCarrier{}
+	Inject(context.Background(), empty)
+	assert.Empty(t, empty)
+}
+
+// TestNewTraceID 测试 New 生成与继承追踪 ID
+func TestNewTraceID(t *testing.T) {
+	t.Parallel()
+
+	ctx := New()
+	assert.Len(t, ctx.TraceID(), 32)
+	assert.Equal(t, ctx.TraceID(), ctx.SpanContext().TraceID.String())
+	assert.True(t, ctx.SpanContext().Flags.IsSampled())
+
+	// UUID 格式的 TraceID 沿用为追踪 ID
+	legacy := New(context.WithValue(context.Background(), TraceIDKey, testLegacyID))
+	assert.Equal(t, testLegacyID, legacy.TraceID())
+	assert.Equal(t, "550e8400e29b41d4a716446655440000", legacy.SpanContext().TraceID.String())
+
+	// 其他格式的 TraceID 原样保留，追踪 ID 重新生成
+	custom := New(context.WithValue(context.Background(), TraceIDKey, "test-trace-id-123"))
+	assert.Equal(t, "test-trace-id-123", custom.TraceID())
+	assert.True(t, custom.SpanContext().TraceID.IsValid())
+
+	// 新的追踪没有 span，不生成无人记录的伪 span ID
+	assert.False(t, ctx.SpanContext().SpanID.IsValid())
+
+	// 从 kctx 父上下文继承完整的追踪信息
+	assert.Equal(t, ctx.SpanContext(), New(ctx).SpanContext())
+}
+
+// TestLegacyTraceKey 测试旧版 UUID TraceID 的兼容模式
+func TestLegacyTraceKey(t *testing.T) {
+	carrier := MapCarrier{"x-trace-id": testLegacyID}
+
+	// 默认不开启兼容模式
+	assert.NotEqual(t, testLegacyID, Extract(carrier).TraceID())
+
+	SetLegacyTraceKey("X-Trace-Id")
+	t.Cleanup(func() { SetLegacyTraceKey("") })
+
+	ctx := Extract(carrier)
+	assert.Equal(t, testLegacyID, ctx.TraceID())
+	assert.Equal(t, "550e8400e29b41d4a716446655440000", ctx.SpanContext().TraceID.String())
+
+	out := HeaderCarrier(http.Header{})
+	Inject(ctx, out)
+	assert.Equal(t, testLegacyID, out.Get("X-Trace-Id"))
+	assert.Empty(t, out.Get(TraceParentHeader), "旧版 TraceID 没有上游 span")
+
+	call, end := StartSpan(ctx, "call")
+	defer end()
+	Inject(call, out)
+	assert.Contains(t, out.Get(TraceParentHeader), "550e8400e29b41d4a716446655440000")
+
+	// traceparent 优先于旧版 TraceID
+	carrier[TraceParentHeader] = testTraceParent
+	assert.Equal(t, testTraceID, Extract(carrier).TraceID())
+
+	// 非 UUID 格式的旧版 TraceID 不被接受
+	assert.NotEqual(t, "abc", Extract(MapCarrier{"X-Trace-Id": "abc"}).TraceID())
+}
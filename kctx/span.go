@@ -0,0 +1,307 @@
+package kctx
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"os"
+	"sync"
+	"time"
+)
+
+// SpanStatus span 的结束状态
+type SpanStatus int
+
+const (
+	StatusUnset SpanStatus = iota // 未设置
+	StatusOK                      // 成功
+	StatusError                   // 失败
+)
+
+// String 返回状态名称
+func (s SpanStatus) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusError:
+		return "error"
+	default:
+		return "unset"
+	}
+}
+
+// SpanData 已结束 span 的记录，交给 SpanProcessor 处理
+type SpanData struct {
+	Name          string         // 操作名称
+	TraceID       TraceID        // 追踪 ID
+	SpanID        SpanID         // 自身的 span ID
+	ParentID      SpanID         // 父 span ID
+	Start         time.Time      // 开始时间
+	End           time.Time      // 结束时间
+	Attributes    map[string]any // 属性
+	Status        SpanStatus     // 结束状态
+	StatusMessage string         // 状态说明，失败时为错误信息
+}
+
+// Duration 返回 span 的耗时
+func (s SpanData) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// SpanProcessor 处理已结束的 span，在调用 End 的 goroutine 中同步调用，实现需并发安全
+type SpanProcessor interface {
+	OnEnd(span SpanData)
+}
+
+// SpanProcessorFunc 函数形式的 SpanProcessor
+type SpanProcessorFunc func(span SpanData)
+
+// OnEnd 实现 SpanProcessor 接口
+func (f SpanProcessorFunc) OnEnd(span SpanData) {
+	f(span)
+}
+
+// EndFunc 结束 span；传入非 nil 的 err 时状态记为 StatusError，未设置状态时记为 StatusOK。
+// 重复调用只有第一次生效
+type EndFunc func(err ...error)
+
+// activeSpanKey 用于在上下文中获取进行中 span 的键
+type activeSpanKey struct{}
+
+// activeSpan 进行中的 span，由 StartSpan 创建的上下文及其衍生上下文共享
+type activeSpan struct {
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+	flags TraceFlags
+}
+
+// spanProcessing span 处理配置
+var spanProcessing = struct {
+	mu         sync.RWMutex
+	processors []SpanProcessor
+}{}
+
+// SetSpanProcessor 设置 span 处理器，替换已有设置；不设置时结束的 span 直接丢弃。
+// 未设置采样标志的 span 不交给处理器
+//
+//	kctx.SetSpanProcessor(kctx.NewSpanRecorder())
+func SetSpanProcessor(processors ...SpanProcessor) {
+	spanProcessing.mu.Lock()
+	defer spanProcessing.mu.Unlock()
+	spanProcessing.processors = processors
+}
+
+// StartSpan 基于 ctx 开启子 span，返回携带子 span ID 的上下文和结束函数。
+// 子上下文沿用追踪 ID、采样标志和 tracestate，元数据同 WithCancel 一样被复制；
+// 父 span ID 取自 ctx 的 SpanContext
+//
+//	ctx, end := kctx.StartSpan(ctx, "db.query")
+//	defer func() { end(err) }()
+func StartSpan(ctx context.Context, name string) (Context, EndFunc) {
+	var child *kCtx
+	if parent, ok := ctx.(*kCtx); ok {
+		child = copyCtx(parent)
+	} else {
+		child = New(ctx).(*kCtx)
+	}
+
+	parent := child.span
+	child.span = SpanContext{
+		TraceID:    parent.TraceID,
+		SpanID:     newSpanID(),
+		Flags:      parent.Flags,
+		TraceState: parent.TraceState,
+	}
+	s := &activeSpan{
+		data: SpanData{
+			Name:     name,
+			TraceID:  child.span.TraceID,
+			SpanID:   child.span.SpanID,
+			ParentID: parent.SpanID,
+			Start:    time.Now(),
+		},
+		flags: child.span.Flags,
+	}
+	child.active = s
+	return child, s.end
+}
+
+// SetSpanAttribute 设置 ctx 中进行中 span 的属性，没有进行中的 span 或 span 已结束时忽略
+func SetSpanAttribute(ctx context.Context, key string, val any) {
+	s := activeSpanOf(ctx)
+	if s == nil || key == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]any)
+	}
+	s.data.Attributes[key] = val
+}
+
+// SetSpanStatus 设置 ctx 中进行中 span 的状态，没有进行中的 span 或 span 已结束时忽略
+func SetSpanStatus(ctx context.Context, status SpanStatus, msg string) {
+	s := activeSpanOf(ctx)
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Status = status
+	s.data.StatusMessage = msg
+}
+
+// activeSpanOf 返回 ctx 中进行中的 span，没有时返回 nil
+func activeSpanOf(ctx context.Context) *activeSpan {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(activeSpanKey{}).(*activeSpan)
+	return s
+}
+
+// end 结束 span 并交给 span 处理器
+func (s *activeSpan) end(err ...error) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	for _, e := range err {
+		if e != nil {
+			s.data.Status = StatusError
+			s.data.StatusMessage = e.Error()
+			break
+		}
+	}
+	if s.data.Status == StatusUnset {
+		s.data.Status = StatusOK
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	if !s.flags.IsSampled() {
+		return
+	}
+	spanProcessing.mu.RLock()
+	processors := spanProcessing.processors
+	spanProcessing.mu.RUnlock()
+	for _, p := range processors {
+		p.OnEnd(data)
+	}
+}
+
+// SpanRecorder 在内存中记录已结束的 span，用于测试
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewSpanRecorder 创建内存 span 记录器
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// OnEnd 实现 SpanProcessor 接口
+func (r *SpanRecorder) OnEnd(span SpanData) {
+	span.Attributes = maps.Clone(span.Attributes)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+// Spans 按结束顺序返回已记录 span 的副本
+func (r *SpanRecorder) Spans() []SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]SpanData, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+// Reset 清空已记录的 span
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+// JSONLinesExporter 以 JSON Lines 格式逐行写出已结束的 span，可由本地采集器读取
+type JSONLinesExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLinesExporter 创建写入 w 的 JSON Lines 导出器
+func NewJSONLinesExporter(w io.Writer) *JSONLinesExporter {
+	return &JSONLinesExporter{w: w}
+}
+
+// OpenJSONLinesExporter 以追加方式打开文件，创建写入该文件的 JSON Lines 导出器
+func OpenJSONLinesExporter(path string) (*JSONLinesExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONLinesExporter(f), nil
+}
+
+// jsonSpan JSON Lines 中 span 的结构
+type jsonSpan struct {
+	Name          string         `json:"name"`
+	TraceID       string         `json:"trace_id"`
+	SpanID        string         `json:"span_id"`
+	ParentID      string         `json:"parent_id,omitempty"`
+	Start         time.Time      `json:"start"`
+	End           time.Time      `json:"end"`
+	DurationMS    float64        `json:"duration_ms"`
+	Attributes    map[string]any `json:"attributes,omitempty"`
+	Status        string         `json:"status"`
+	StatusMessage string         `json:"status_message,omitempty"`
+}
+
+// OnEnd 实现 SpanProcessor 接口，无法序列化的 span 被丢弃
+func (e *JSONLinesExporter) OnEnd(span SpanData) {
+	js := jsonSpan{
+		Name:          span.Name,
+		TraceID:       span.TraceID.String(),
+		SpanID:        span.SpanID.String(),
+		Start:         span.Start,
+		End:           span.End,
+		DurationMS:    float64(span.Duration().Microseconds()) / 1000,
+		Attributes:    span.Attributes,
+		Status:        span.Status.String(),
+		StatusMessage: span.StatusMessage,
+	}
+	if span.ParentID.IsValid() {
+		js.ParentID = span.ParentID.String()
+	}
+	line, err := json.Marshal(js)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, _ = e.w.Write(append(line, '\n'))
+}
+
+// Close 底层 Writer 实现 io.Closer 时关闭
+func (e *JSONLinesExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if c, ok := e.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package kctx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordSpans 设置内存记录器作为 span 处理器，测试结束后恢复
+func recordSpans(t *testing.T) *SpanRecorder {
+	t.Helper()
+	rec := NewSpanRecorder()
+	SetSpanProcessor(rec)
+	t.Cleanup(func() { SetSpanProcessor() })
+	return rec
+}
+
+// TestStartSpan 测试子 span 的 ID、父子关系和记录内容
+func TestStartSpan(t *testing.T) {
+	rec := recordSpans(t)
+
+	root := New()
+	root.Set("user_id", "1001")
+	ctx, end := StartSpan(root, "db.query")
+	sc := ctx.SpanContext()
+	assert.Equal(t, root.TraceID(), ctx.TraceID())
+	assert.Equal(t, root.SpanContext().TraceID, sc.TraceID)
+	assert.NotEqual(t, root.SpanContext().SpanID, sc.SpanID)
+	assert.True(t, sc.IsValid())
+	assert.Equal(t, "1001", ctx.Get("user_id"))
+
+	// 衍生上下文共享进行中的 span
+	derived, cancel := WithCancel(ctx)
+	defer cancel()
+	SetSpanAttribute(derived, "db.table", "orders")
+	SetSpanAttribute(New(derived), "db.rows", 3)
+
+	grandchild, endChild := StartSpan(ctx, "db.scan")
+	endChild()
+	assert.Empty(t, activeSpanOf(root), "根上下文没有进行中的 span")
+	end()
+	end(errors.New("ignored")) // 重复调用不生效
+
+	spans := rec.Spans()
+	require.Len(t, spans, 2)
+	scan, query := spans[0], spans[1]
+
+	assert.Equal(t, "db.scan", scan.Name)
+	assert.Equal(t, grandchild.SpanContext().SpanID, scan.SpanID)
+	assert.Equal(t, sc.SpanID, scan.ParentID)
+
+	assert.Equal(t, "db.query", query.Name)
+	assert.Equal(t, sc.TraceID, query.TraceID)
+	assert.Equal(t, sc.SpanID, query.SpanID)
+	assert.False(t, query.ParentID.IsValid(), "新追踪的第一个 span 为根 span")
+	assert.Equal(t, map[string]any{"db.table": "orders", "db.rows": 3}, query.Attributes)
+	assert.Equal(t, StatusOK, query.Status)
+	assert.False(t, query.End.Before(query.Start))
+	assert.GreaterOrEqual(t, query.Duration(), scan.Duration())
+
+	// 注入的是子 span ID
+	out := MapCarrier{}
+	Inject(ctx, out)
+	assert.Contains(t, out[TraceParentHeader], sc.SpanID.String())
+}
+
+// TestSpanStatus 测试 span 的结束状态
+func TestSpanStatus(t *testing.T) {
+	rec := recordSpans(t)
+
+	ctx, end := StartSpan(context.Background(), "failed")
+	end(nil, errors.New("connection refused"))
+
+	ctx2, end2 := StartSpan(ctx, "explicit")
+	SetSpanStatus(ctx2, StatusError, "timeout")
+	end2()
+	SetSpanAttribute(ctx2, "late", true) // 结束后忽略
+
+	spans := rec.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, StatusError, spans[0].Status)
+	assert.Equal(t, "connection refused", spans[0].StatusMessage)
+	assert.Equal(t, StatusError, spans[1].Status)
+	assert.Equal(t, "timeout", spans[1].StatusMessage)
+	assert.Nil(t, spans[1].Attributes)
+	assert.Equal(t, "error", StatusError.String())
+
+	rec.Reset()
+	assert.Empty(t, rec.Spans())
+
+	// 未采样的 span 不交给处理器
+	unsampled := Extract(MapCarrier{TraceParentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"})
+	_, end3 := StartSpan(unsampled, "unsampled")
+	end3()
+	assert.Empty(t, rec.Spans())
+
+	// 上游 span 作为父 span
+	remote := Extract(MapCarrier{TraceParentHeader: testTraceParent})
+	_, end4 := StartSpan(remote, "remote")
+	end4()
+	require.Len(t, rec.Spans(), 1)
+	assert.Equal(t, "00f067aa0ba902b7", rec.Spans()[0].ParentID.String())
+	assert.Equal(t, testTraceID, rec.Spans()[0].TraceID.String())
+}
+
+// TestJSONLinesExporter 测试以 JSON Lines 格式导出 span
+func TestJSONLinesExporter(t *testing.T) {
+	var buf bytes.Buffer
+	SetSpanProcessor(NewJSONLinesExporter(&buf))
+	t.Cleanup(func() { SetSpanProcessor() })
+
+	root := New()
+	ctx, end := StartSpan(root, "rpc.call")
+	SetSpanAttribute(ctx, "peer", "order-svc")
+	end(errors.New("unavailable"))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "rpc.call", got["name"])
+	assert.Equal(t, root.TraceID(), got["trace_id"])
+	assert.Equal(t, ctx.SpanContext().SpanID.String(), got["span_id"])
+	assert.NotContains(t, got, "parent_id", "根 span 没有父 span")
+	assert.Equal(t, map[string]any{"peer": "order-svc"}, got["attributes"])
+	assert.Equal(t, "error", got["status"])
+	assert.Equal(t, "unavailable", got["status_message"])
+	assert.Contains(t, got, "duration_ms")
+
+	// 写入文件，每个 span 一行
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := OpenJSONLinesExporter(path)
+	require.NoError(t, err)
+	SetSpanProcessor(exporter)
+	for _, name := range []string{"a", "b"} {
+		_, end := StartSpan(root, name)
+		end()
+	}
+	require.NoError(t, exporter.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line jsonSpan
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		names = append(names, line.Name)
+	}
+	assert.Equal(t, []string{"a", "b"}, names)
+}
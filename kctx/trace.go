@@ -0,0 +1,209 @@
+package kctx
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SpanContextKey 用于在上下文中获取 SpanContext 的键，
+// 可通过 context.Value(SpanContextKey) 从父上下文继承追踪信息。
+const SpanContextKey = "SpanContext"
+
+// ErrInvalidTraceParent traceparent 格式不符合 W3C Trace Context 规范
+var ErrInvalidTraceParent = errors.New("kctx: invalid traceparent")
+
+type (
+	// TraceID W3C Trace Context 的 16 字节追踪 ID
+	TraceID [16]byte
+
+	// SpanID W3C Trace Context 的 8 字节 span ID
+	SpanID [8]byte
+
+	// TraceFlags W3C Trace Context 的追踪标志
+	TraceFlags byte
+
+	// SpanContext 跨进程传播的追踪信息，对应 traceparent 和 tracestate
+	SpanContext struct {
+		TraceID    TraceID
+		SpanID     SpanID
+		Flags      TraceFlags
+		TraceState string // 原样传播的 tracestate，格式非法时为空
+		Remote     bool   // 是否从上游提取
+	}
+)
+
+// FlagSampled 采样标志
+const FlagSampled TraceFlags = 0x01
+
+// IsValid 判断追踪 ID 是否有效（非全零）
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String 返回 32 位小写十六进制文本
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid 判断 span ID 是否有效（非全零）
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// String 返回 16 位小写十六进制文本
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsSampled 判断是否设置了采样标志
+func (f TraceFlags) IsSampled() bool {
+	return f&FlagSampled != 0
+}
+
+// IsValid 判断追踪 ID 和 span ID 是否均有效
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// TraceParent 返回 version 00 的 traceparent 文本，无效时返回空字符串
+func (sc SpanContext) TraceParent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{byte(sc.Flags)})
+}
+
+// ParseTraceParent 解析 traceparent，形如 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+// 高于 00 的版本按 00 的格式解析前 55 个字符，忽略其后的扩展字段
+func ParseTraceParent(s string) (SpanContext, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return SpanContext{}, ErrInvalidTraceParent
+	}
+	version, ok := parseHex(s[:2])
+	if !ok || version[0] == 0xff || (version[0] == 0 && len(s) != 55) || (len(s) > 55 && s[55] != '-') {
+		return SpanContext{}, ErrInvalidTraceParent
+	}
+	traceID, ok1 := parseHex(s[3:35])
+	spanID, ok2 := parseHex(s[36:52])
+	flags, ok3 := parseHex(s[53:55])
+	if !ok1 || !ok2 || !ok3 {
+		return SpanContext{}, ErrInvalidTraceParent
+	}
+
+	var sc SpanContext
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = TraceFlags(flags[0])
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceParent
+	}
+	return sc, nil
+}
+
+// parseHex 解析小写十六进制文本，W3C 规范不允许大写
+func parseHex(s string) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+// maxTraceStateMembers tracestate 最多包含的成员数
+const maxTraceStateMembers = 32
+
+// normalizeTraceState 校验并整理 tracestate，去掉空成员和多余空白，格式非法时返回空字符串
+func normalizeTraceState(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	members := make([]string, 0, 4)
+	seen := make(map[string]bool)
+	for _, m := range strings.Split(s, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(m, "=")
+		if !ok || !validTraceStateKey(key) || !validTraceStateValue(value) || seen[key] {
+			return ""
+		}
+		seen[key] = true
+		members = append(members, m)
+	}
+	if len(members) > maxTraceStateMembers {
+		return ""
+	}
+	return strings.Join(members, ",")
+}
+
+// validTraceStateKey 校验 tracestate 的键：小写字母开头，可含 tenant@system 形式
+func validTraceStateKey(key string) bool {
+	if key == "" || len(key) > 256 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case i > 0 && (c == '_' || c == '-' || c == '*' || c == '/' || c == '@'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validTraceStateValue 校验 tracestate 的值：可打印 ASCII，不含 "," 和 "="，不以空格结尾
+func validTraceStateValue(value string) bool {
+	if value == "" || len(value) > 256 || value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTraceID 将 TraceID 字符串解析为追踪 ID，支持 32 位十六进制和 UUID 两种格式
+func parseTraceID(s string) (TraceID, bool) {
+	var id TraceID
+	if b, ok := parseHex(s); ok && len(b) == len(id) {
+		copy(id[:], b)
+		return id, id.IsValid()
+	}
+	u, err := uuid.Parse(s)
+	if err != nil || len(s) != 36 {
+		return id, false
+	}
+	id = TraceID(u)
+	return id, id.IsValid()
+}
+
+// newTraceID 生成随机的追踪 ID
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+// newSpanID 生成随机的 span ID
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}
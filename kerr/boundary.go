@@ -0,0 +1,166 @@
+package kerr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// boundaryRange 错误码区间到上层错误的映射，区间为闭区间 [min, max]
+type boundaryRange struct {
+	min int
+	max int
+	to  Error
+}
+
+// Boundary 层边界，将下层返回的错误按映射重新编码为上层的公开错误
+// 原错误作为私有 cause 保留，只能通过 PrivateCause 取到：Error()、errors.Is/As、Chain 不会越过边界，
+// 原错误的字段和详情也不会出现在上层错误的 Fields、DetailsOf 中；Internal、Debug 级别的 %+v 和 JSON 输出包含原错误
+//
+//	var repoToAPI = kerr.NewBoundary("repo->api").
+//		Map(api.OrderNotFound, repo.ErrNoRow).
+//		MapRange(40000, 49999, api.ServiceBusy).
+//		Pass(kerr.ValidationFailed)
+//
+//	return repoToAPI.Cross(err)
+type Boundary struct {
+	name     string
+	codes    map[int]Error // 值为 nil 表示原样通过
+	ranges   []boundaryRange
+	fallback Error
+}
+
+// NewBoundary 创建层边界，未匹配映射的错误默认重新编码为 SystemError
+func NewBoundary(name string) *Boundary {
+	return &Boundary{name: name, codes: map[int]Error{}, fallback: SystemError}
+}
+
+// clone 复制边界，避免修改共享的映射
+func (b *Boundary) clone() *Boundary {
+	c := *b
+	c.codes = make(map[int]Error, len(b.codes))
+	for k, v := range b.codes {
+		c.codes[k] = v
+	}
+	c.ranges = append([]boundaryRange(nil), b.ranges...)
+	return &c
+}
+
+// Name 返回边界名称
+func (b *Boundary) Name() string {
+	return b.name
+}
+
+// Map 将与 from 错误码相同的错误重新编码为 to，返回新的边界
+func (b *Boundary) Map(to Error, from ...Error) *Boundary {
+	c := b.clone()
+	for _, f := range from {
+		c.codes[f.Code()] = to
+	}
+	return c
+}
+
+// MapRange 将错误码区间 [min, max] 内的错误重新编码为 to，返回新的边界
+// 精确错误码优先于区间，后设置的区间优先匹配
+func (b *Boundary) MapRange(min, max int, to Error) *Boundary {
+	c := b.clone()
+	c.ranges = append([]boundaryRange{{min: min, max: max, to: to}}, c.ranges...)
+	return c
+}
+
+// Pass 允许与 from 错误码相同的错误原样通过边界，返回新的边界
+func (b *Boundary) Pass(from ...Error) *Boundary {
+	c := b.clone()
+	for _, f := range from {
+		c.codes[f.Code()] = nil
+	}
+	return c
+}
+
+// Fallback 设置未匹配映射的错误重新编码的目标，为 nil 时未匹配的错误原样通过，返回新的边界
+func (b *Boundary) Fallback(to Error) *Boundary {
+	c := b.clone()
+	c.fallback = to
+	return c
+}
+
+// Cross 将错误转换为上层的公开错误，nil 返回 nil
+// 非 kerr 错误先按 Translate 转换后再匹配映射；已经过该边界的错误不再重复编码
+func (b *Boundary) Cross(err error) Error {
+	if err == nil {
+		return nil
+	}
+	e := Translate(err)
+	var p *privateError
+	if errors.As(err, &p) && p.boundary == b.name {
+		return e
+	}
+
+	to := b.target(e.Code())
+	if to == nil {
+		return e
+	}
+	return to.Wrap(&privateError{err: err, boundary: b.name})
+}
+
+// target 按错误码查找重新编码的目标，nil 表示原样通过
+func (b *Boundary) target(code int) Error {
+	if to, ok := b.codes[code]; ok {
+		return to
+	}
+	for _, r := range b.ranges {
+		if code >= r.min && code <= r.max {
+			return r.to
+		}
+	}
+	return b.fallback
+}
+
+// PrivateCause 返回错误链上最近一个层边界保留的原错误，未经过边界时返回 nil
+func PrivateCause(err error) error {
+	var p *privateError
+	if errors.As(err, &p) {
+		return p.err
+	}
+	return nil
+}
+
+// privateError 层边界保留的原错误
+// 不实现 Unwrap，错误链到此为止，原错误只能通过 PrivateCause 访问
+type privateError struct {
+	err      error
+	boundary string
+}
+
+// Error 返回不含原错误信息的占位文本
+func (p *privateError) Error() string {
+	return "private cause of boundary " + p.boundary
+}
+
+// Format 支持 fmt.Printf("%+v", err)，同原错误
+func (p *privateError) Format(f fmt.State, c rune) {
+	if c == 'v' && f.Flag('+') {
+		fmt.Fprint(f, p.verbose(fullRender()))
+		return
+	}
+	fmt.Fprint(f, p.text(fullRender()))
+}
+
+// rawText 返回占位文本，上层错误的 Error() 不包含原错误信息
+func (p *privateError) rawText() string {
+	return p.Error()
+}
+
+// text 返回原错误 %s、%v 的输出
+func (p *privateError) text(o renderOptions) string {
+	return textOf(p.err, o)
+}
+
+// verbose 返回原错误 %+v 的输出
+func (p *privateError) verbose(o renderOptions) string {
+	return verboseOf(p.err, o)
+}
+
+// marshalJSON 按输出选项序列化原错误
+func (p *privateError) marshalJSON(o renderOptions) ([]byte, error) {
+	return marshalOf(p.err, o)
+}
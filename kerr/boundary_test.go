@@ -0,0 +1,118 @@
+package kerr
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var (
+	errRepoMissing = New(96701, "row missing")
+	errRepoBusy    = New(96702, "db pool exhausted")
+	errAPINotFound = New(96711, "resource not found").WithDisplay("资源不存在")
+	errAPIBusy     = New(96712, "service busy").WithDisplay("服务繁忙")
+)
+
+func TestBoundaryCross(t *testing.T) {
+	b := NewBoundary("repo->api").
+		Map(errAPINotFound, errRepoMissing, NoData).
+		MapRange(96700, 96709, errAPIBusy).
+		Pass(ValidationFailed)
+
+	orig := errRepoMissing.Wrap(errors.New("select 1")).WithField("table", "orders")
+	got := b.Cross(orig)
+	if got.Code() != errAPINotFound.Code() || got.Display() != "资源不存在" {
+		t.Fatalf("expected re-coded error, got %v", got)
+	}
+	if errors.Is(got, errRepoMissing) || !errors.Is(got, errAPINotFound) {
+		t.Errorf("private cause must not be reachable through errors.Is")
+	}
+	var inner *KError
+	if errors.As(got, &inner); inner != got {
+		t.Errorf("errors.As must not reach the private cause, got %v", inner)
+	}
+	if s := got.Error(); strings.Contains(s, "row missing") || strings.Contains(s, "select 1") {
+		t.Errorf("Error() must not include the private cause: %q", s)
+	}
+	if PrivateCause(got) != orig || !errors.Is(PrivateCause(got), errRepoMissing) {
+		t.Errorf("PrivateCause = %v", PrivateCause(got))
+	}
+	if _, found := FindCode(got, errRepoMissing.Code()); found || len(Chain(got)) != 1 || RootCause(got) != got {
+		t.Errorf("Chain must stop at the boundary: %v", Chain(got))
+	}
+	if _, ok := got.Fields()["table"]; ok {
+		t.Errorf("private cause fields must not leak: %v", got.Fields())
+	}
+
+	if got := b.Cross(errRepoBusy); got.Code() != errAPIBusy.Code() {
+		t.Errorf("range mapping: got %d", got.Code())
+	}
+	if got := b.Cross(sql.ErrNoRows); got.Code() != errAPINotFound.Code() || errors.Is(got, sql.ErrNoRows) || PrivateCause(got) != sql.ErrNoRows {
+		t.Errorf("foreign errors must be translated first, got %v", got)
+	}
+	if got := b.Cross(ValidationFailed); got != ValidationFailed { //kerrlint:ignore kerrcompare Pass 的错误原样通过边界
+		t.Errorf("passed errors must cross unchanged, got %v", got)
+	}
+	if got := b.Cross(InvalidState); got.Code() != SystemError.Code() || errors.Is(got, InvalidState) || PrivateCause(got) != InvalidState { //kerrlint:ignore kerrcompare PrivateCause 返回原实例
+		t.Errorf("unmapped errors must fall back to SystemError, got %v", got)
+	}
+	if got := b.Fallback(nil).Cross(InvalidState); got != InvalidState { //kerrlint:ignore kerrcompare 未映射的错误原样通过边界
+		t.Errorf("nil fallback must let unmapped errors through, got %v", got)
+	}
+	if b.Cross(nil) != nil {
+		t.Errorf("Cross(nil) must be nil")
+	}
+
+	// 已经过该边界的错误不再重复编码，保留外层包装
+	if again := b.Cross(fmt.Errorf("handler: %w", got)); again.Code() != got.Code() || PrivateCause(again) != PrivateCause(got) || !strings.Contains(again.Error(), "handler: ") {
+		t.Errorf("crossing twice must keep the first translation, got %v", again)
+	}
+}
+
+func TestBoundaryImmutable(t *testing.T) {
+	base := NewBoundary("svc")
+	mapped := base.Map(errAPINotFound, errRepoMissing)
+	if base.Cross(errRepoMissing).Code() != SystemError.Code() {
+		t.Errorf("Map must not modify the original boundary")
+	}
+	if mapped.Cross(errRepoMissing).Code() != errAPINotFound.Code() || mapped.Name() != "svc" {
+		t.Errorf("unexpected mapped boundary")
+	}
+}
+
+func TestBoundaryExposure(t *testing.T) {
+	b := NewBoundary("repo->api").Map(errAPINotFound, errRepoMissing)
+	orig := errRepoMissing.WithDetails(FieldViolation{Field: "id", Reason: "unknown"}).WithField("sql", "select *")
+	got := b.Cross(orig)
+
+	setExposure(t, Public)
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, got)
+	if s := rec.Body.String(); strings.Contains(s, "row missing") {
+		t.Errorf("public output must not include the private cause: %s", s)
+	}
+	if p := ToProblem(got, ProblemOptions{}); p.Extensions["sql"] != nil || p.Extensions["details"] != nil {
+		t.Errorf("problem must not include private fields or details: %v", p.Extensions)
+	}
+	if len(DetailsOf[FieldViolation](got)) != 0 {
+		t.Errorf("DetailsOf must not cross the boundary")
+	}
+
+	// 日志输出不受暴露级别影响，包含原错误
+	if s := fmt.Sprintf("%+v", got); !strings.Contains(s, "Caused by: row missing") || !strings.Contains(s, "sql=select *") {
+		t.Errorf("%%+v must include the private cause: %s", s)
+	}
+	if s := got.ToJSON(); !strings.Contains(s, `"cause":{"code":96701`) {
+		t.Errorf("JSON must nest the private cause: %s", s)
+	}
+
+	SetExposure(Internal)
+	rec = httptest.NewRecorder()
+	WriteHTTP(rec, got)
+	if s := rec.Body.String(); !strings.Contains(s, "row missing") {
+		t.Errorf("internal output must include the private cause: %s", s)
+	}
+}
@@ -0,0 +1,202 @@
+package kerr
+
+import (
+	"context"
+	"errors"
+)
+
+// Category 错误分类，默认按错误码号段划分
+type Category int
+
+const (
+	CategoryUnknown   Category = iota // 未知错误（非 kerr 错误或号段外的错误码）
+	CategorySuccess                   // 0 - 成功
+	CategorySystem                    // 1xxxx - 系统/基础设施错误
+	CategoryAuth                      // 2xxxx - 用户/认证/权限错误
+	CategoryBusiness                  // 3xxxx - 业务逻辑错误
+	CategoryExternal                  // 4xxxx - 外部依赖错误
+	CategoryFramework                 // 5xxxx - 核心组件/框架错误
+)
+
+// String 返回分类名称
+func (c Category) String() string {
+	switch c {
+	case CategorySuccess:
+		return "success"
+	case CategorySystem:
+		return "system"
+	case CategoryAuth:
+		return "auth"
+	case CategoryBusiness:
+		return "business"
+	case CategoryExternal:
+		return "external"
+	case CategoryFramework:
+		return "framework"
+	default:
+		return "unknown"
+	}
+}
+
+// Severity 错误严重程度，数值越大越严重
+type Severity int
+
+const (
+	SeverityInfo     Severity = iota // 提示，如成功或预期内的结果
+	SeverityWarning                  // 警告，如参数错误、权限不足等客户端错误
+	SeverityError                    // 错误，如外部依赖或框架组件异常
+	SeverityCritical                 // 严重，如系统错误、panic，需要告警
+)
+
+// String 返回严重程度名称
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// attrs 错误码属性
+type attrs struct {
+	category   Category
+	severity   Severity
+	retryable  bool
+	temporary  bool
+	httpStatus int // 0 表示使用 HTTP 状态码映射表
+}
+
+// retryableCodes 默认可重试的错误码（号段外的补充）
+var retryableCodes = map[int]bool{
+	10002: true, // TimeoutError
+	50002: true, // ResourcePoolEmpty
+	50003: true, // ServiceNotReady
+}
+
+// temporaryCodes 默认为临时错误但不建议立即重试的错误码
+var temporaryCodes = map[int]bool{
+	20006: true, // QuotaExceeded
+}
+
+// defaultAttrs 按错误码号段推导默认属性
+func defaultAttrs(code int) attrs {
+	return categoryAttrs(categoryOfCode(code), code)
+}
+
+// categoryAttrs 按分类推导错误码的默认严重程度和可重试性
+func categoryAttrs(c Category, code int) attrs {
+	a := attrs{category: c}
+	switch a.category {
+	case CategorySuccess:
+		a.severity = SeverityInfo
+	case CategoryAuth, CategoryBusiness:
+		a.severity = SeverityWarning
+	case CategoryExternal:
+		a.severity = SeverityError
+		a.retryable = true // 外部依赖错误默认可重试
+	case CategoryFramework:
+		a.severity = SeverityError
+	case CategorySystem:
+		a.severity = SeverityCritical
+	default:
+		// 号段外的错误码（如应用自定义的 6xxxx）与非 kerr 错误相同，默认为 SeverityError，注册时可通过 WithSeverity 调整
+		a.severity = SeverityError
+	}
+	if retryableCodes[code] {
+		a.retryable = true
+	}
+	a.temporary = a.retryable || temporaryCodes[code]
+	return a
+}
+
+// categoryOfCode 按错误码号段返回分类
+func categoryOfCode(code int) Category {
+	switch {
+	case code == 0:
+		return CategorySuccess
+	case code >= 10000 && code < 20000:
+		return CategorySystem
+	case code >= 20000 && code < 30000:
+		return CategoryAuth
+	case code >= 30000 && code < 40000:
+		return CategoryBusiness
+	case code >= 40000 && code < 50000:
+		return CategoryExternal
+	case code >= 50000 && code < 60000:
+		return CategoryFramework
+	default:
+		return CategoryUnknown
+	}
+}
+
+// CategoryOf 返回错误的分类
+// nil 视为成功，非 kerr 错误返回 CategoryUnknown
+func CategoryOf(err error) Category {
+	if err == nil {
+		return CategorySuccess
+	}
+	var e Error
+	if !errors.As(err, &e) {
+		return CategoryUnknown
+	}
+	return attrsOf(e.Code()).category
+}
+
+// SeverityOf 返回错误的严重程度
+// nil 视为 SeverityInfo，非 kerr 错误视为 SeverityError
+func SeverityOf(err error) Severity {
+	if err == nil {
+		return SeverityInfo
+	}
+	var e Error
+	if !errors.As(err, &e) {
+		return SeverityError
+	}
+	return attrsOf(e.Code()).severity
+}
+
+// IsRetryable 判断错误是否可重试
+// 非 kerr 错误按超时判断：context.DeadlineExceeded 或实现 Timeout() bool 且返回 true 的错误可重试
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e Error
+	if errors.As(err, &e) {
+		return attrsOf(e.Code()).retryable
+	}
+	return isTimeout(err)
+}
+
+// IsTemporary 判断错误是否为临时错误
+// 非 kerr 错误额外识别实现 Temporary() bool 且返回 true 的错误
+func IsTemporary(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e Error
+	if errors.As(err, &e) {
+		return attrsOf(e.Code()).temporary
+	}
+	var t interface{ Temporary() bool }
+	if errors.As(err, &t) && t.Temporary() {
+		return true
+	}
+	return isTimeout(err)
+}
+
+// isTimeout 判断非 kerr 错误是否为超时错误
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
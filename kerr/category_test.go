@@ -0,0 +1,121 @@
+package kerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestCategoryOf(t *testing.T) {
+	cases := []struct {
+		err      error
+		category Category
+	}{
+		{nil, CategorySuccess},
+		{Succ, CategorySuccess},
+		{PanicError, CategorySystem},
+		{TokenExpired, CategoryAuth},
+		{NoData.Wrap(errors.New("cause")), CategoryBusiness},
+		{fmt.Errorf("query: %w", DBError), CategoryExternal},
+		{ServiceNotReady, CategoryFramework},
+		{New(96400, "out of range"), CategoryUnknown},
+		{errors.New("plain"), CategoryUnknown},
+	}
+	for _, c := range cases {
+		if got := CategoryOf(c.err); got != c.category {
+			t.Errorf("CategoryOf(%v) = %s, want %s", c.err, got, c.category)
+		}
+	}
+}
+
+func TestSeverityOf(t *testing.T) {
+	cases := []struct {
+		err      error
+		severity Severity
+	}{
+		{nil, SeverityInfo},
+		{ValidationFailed, SeverityWarning},
+		{Forbidden, SeverityWarning},
+		{NetworkError, SeverityError},
+		{PanicError, SeverityCritical},
+		{errors.New("plain"), SeverityError},
+		{New(60001, "order not found"), SeverityError},
+	}
+	for _, c := range cases {
+		if got := SeverityOf(c.err); got != c.severity {
+			t.Errorf("SeverityOf(%v) = %s, want %s", c.err, got, c.severity)
+		}
+	}
+
+	// 号段外的错误码可在注册时调整严重程度
+	t.Cleanup(func() { unregister(96510) })
+	if got := SeverityOf(MustRegister(New(96510, "ledger mismatch"), WithSeverity(SeverityCritical))); got != SeverityCritical {
+		t.Errorf("registered severity must override the default, got %s", got)
+	}
+}
+
+func TestIsRetryableTemporary(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+		temporary bool
+	}{
+		{nil, false, false},
+		{TimeoutError, true, true},
+		{NetworkError.Wrap(errors.New("reset")), true, true},
+		{ServiceNotReady, true, true},
+		{QuotaExceeded, false, true},
+		{ValidationFailed, false, false},
+		{SystemError, false, false},
+		{context.DeadlineExceeded, true, true},
+		{&net.DNSError{IsTimeout: true}, true, true},
+		{errors.New("plain"), false, false},
+	}
+	for _, c := range cases {
+		if got := IsRetryable(c.err); got != c.retryable {
+			t.Errorf("IsRetryable(%v) = %v, want %v", c.err, got, c.retryable)
+		}
+		if got := IsTemporary(c.err); got != c.temporary {
+			t.Errorf("IsTemporary(%v) = %v, want %v", c.err, got, c.temporary)
+		}
+	}
+}
+
+func TestRegisterOverrideAttrs(t *testing.T) {
+	t.Cleanup(func() { unregister(36001, 96500, 96501) })
+	lockConflict := MustRegister(
+		New(36001, "optimistic lock conflict"),
+		WithRetryable(true),
+		WithSeverity(SeverityInfo),
+		WithHTTPStatus(409),
+	)
+	if !IsRetryable(lockConflict) || !IsTemporary(lockConflict) {
+		t.Errorf("expected registered override to make the error retryable")
+	}
+	if SeverityOf(lockConflict) != SeverityInfo {
+		t.Errorf("expected severity override, got %s", SeverityOf(lockConflict))
+	}
+	if CategoryOf(lockConflict) != CategoryBusiness {
+		t.Errorf("expected category to keep the range default, got %s", CategoryOf(lockConflict))
+	}
+	if HTTPStatus(lockConflict) != 409 {
+		t.Errorf("expected HTTP status override, got %d", HTTPStatus(lockConflict))
+	}
+
+	legacy := MustRegister(New(96500, "legacy gateway error"), WithCategory(CategoryExternal))
+	if CategoryOf(legacy) != CategoryExternal {
+		t.Errorf("expected category override, got %s", CategoryOf(legacy))
+	}
+	if SeverityOf(legacy) != SeverityError || !IsRetryable(legacy) || !IsTemporary(legacy) {
+		t.Errorf("expected severity and retryability derived from the overridden category, got %s %v",
+			SeverityOf(legacy), IsRetryable(legacy))
+	}
+
+	// 显式指定的属性优先于按分类推导的默认值，与选项顺序无关
+	pinned := MustRegister(New(96501, "legacy batch error"), WithRetryable(false), WithCategory(CategoryExternal))
+	if SeverityOf(pinned) != SeverityError || IsRetryable(pinned) {
+		t.Errorf("expected explicit retryability to win, got %s %v", SeverityOf(pinned), IsRetryable(pinned))
+	}
+}
@@ -0,0 +1,122 @@
+package kerr
+
+import (
+	"errors"
+	"sync"
+)
+
+// defaultCode 非 kerr 错误的错误码，默认为 SystemError
+var defaultCode = struct {
+	mu   sync.RWMutex
+	code int
+}{
+	code: 10000, // SystemError
+}
+
+// SetDefaultCode 设置 CodeOf 对非 kerr 错误返回的错误码
+func SetDefaultCode(code int) {
+	defaultCode.mu.Lock()
+	defer defaultCode.mu.Unlock()
+	defaultCode.code = code
+}
+
+// CodeOf 返回错误链上第一个 kerr 错误的错误码
+// nil 返回 0，错误链上没有 kerr 错误时返回 SetDefaultCode 设置的错误码（默认为 SystemError）
+func CodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	for _, e := range Chain(err) {
+		if ke, ok := e.(Error); ok {
+			return ke.Code()
+		}
+	}
+	defaultCode.mu.RLock()
+	defer defaultCode.mu.RUnlock()
+	return defaultCode.code
+}
+
+// Chain 按深度优先顺序返回错误树上的所有错误，第一个元素为 err 本身
+// 支持 Unwrap() error、Go 1.20 的 Unwrap() []error（如 errors.Join）以及 MultiError 的子错误
+func Chain(err error) []error {
+	var chain []error
+	walkChain(err, func(err error) bool {
+		chain = append(chain, err)
+		return true
+	})
+	return chain
+}
+
+// walkChain 深度优先遍历错误链，visit 返回 false 时不再深入该错误的 cause，不越过层边界保留的私有 cause
+func walkChain(err error, visit func(err error) bool) {
+	var walk func(err error)
+	walk = func(err error) {
+		for err != nil {
+			if _, ok := err.(*privateError); ok {
+				return
+			}
+			if !visit(err) {
+				return
+			}
+			switch x := err.(type) {
+			case interface{ Errors() []error }:
+				for _, child := range x.Errors() {
+					walk(child)
+				}
+				return
+			case interface{ Unwrap() []error }:
+				for _, child := range x.Unwrap() {
+					walk(child)
+				}
+				return
+			}
+			err = errors.Unwrap(err)
+		}
+	}
+	walk(err)
+}
+
+// RootCause 返回最内层的错误，遇到多个 cause 时沿第一个 cause 继续查找，不越过层边界保留的私有 cause
+// err 为 nil 时返回 nil
+func RootCause(err error) error {
+	for err != nil {
+		var next error
+		switch x := err.(type) {
+		case interface{ Errors() []error }:
+			if children := x.Errors(); len(children) > 0 {
+				next = children[0]
+			}
+		case interface{ Unwrap() []error }:
+			if children := x.Unwrap(); len(children) > 0 {
+				next = children[0]
+			}
+		default:
+			next = errors.Unwrap(err)
+		}
+		if _, ok := next.(*privateError); next == nil || ok {
+			return err
+		}
+		err = next
+	}
+	return nil
+}
+
+// FindCode 按深度优先顺序查找错误树上错误码为 code 的第一个 kerr 错误
+func FindCode(err error, code int) (Error, bool) {
+	for _, e := range Chain(err) {
+		if ke, ok := e.(Error); ok && ke.Code() == code {
+			return ke, true
+		}
+	}
+	return nil, false
+}
+
+// AsKError 按深度优先顺序查找错误树上的第一个 *KError
+func AsKError(err error) (*KError, bool) {
+	for _, e := range Chain(err) {
+		if ke, ok := e.(*KError); ok {
+			return ke, true
+		}
+	}
+	return nil, false
+}
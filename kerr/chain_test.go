@@ -0,0 +1,117 @@
+package kerr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+// multiCause 实现 Go 1.20 Unwrap() []error 的测试错误
+type multiCause struct {
+	errs []error
+}
+
+func (m multiCause) Error() string   { return "multi cause" }
+func (m multiCause) Unwrap() []error { return m.errs }
+
+func TestCodeOf(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{Succ, 0},
+		{NoData, NoData.Code()},
+		{fmt.Errorf("query: %w", DBError.Wrap(io.EOF)), DBError.Code()},
+		{errors.Join(io.EOF, fmt.Errorf("x: %w", TimeoutError)), TimeoutError.Code()},
+		{&multiCause{errs: []error{os.ErrNotExist, NoData}}, NoData.Code()},
+		{Join(io.EOF, ValidationFailed), ValidationFailed.Code()},
+		{io.EOF, SystemError.Code()},
+	}
+	for _, c := range cases {
+		if got := CodeOf(c.err); got != c.want {
+			t.Errorf("CodeOf(%v) = %d, want %d", c.err, got, c.want)
+		}
+	}
+
+	SetDefaultCode(ThirdPartyError.Code())
+	defer SetDefaultCode(SystemError.Code())
+	if got := CodeOf(io.EOF); got != ThirdPartyError.Code() {
+		t.Errorf("expected configured default code, got %d", got)
+	}
+}
+
+func TestChain(t *testing.T) {
+	inner := fmt.Errorf("read: %w", io.EOF)
+	joined := errors.Join(NoData, inner)
+	err := SystemError.Wrap(&multiCause{errs: []error{joined, os.ErrClosed}})
+
+	chain := Chain(err)
+	want := []error{err, errors.Unwrap(err), joined, NoData, inner, io.EOF, os.ErrClosed}
+	if len(chain) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(chain), chain)
+	}
+	for i := range want {
+		if chain[i] != want[i] {
+			t.Errorf("chain[%d] = %v, want %v", i, chain[i], want[i])
+		}
+	}
+	if Chain(nil) != nil {
+		t.Errorf("expected empty chain for nil")
+	}
+
+	multi := Join(NoData.Wrap(io.EOF), ValidationFailed)
+	if chain := Chain(multi); len(chain) != 4 || chain[2] != io.EOF || chain[3] != ValidationFailed { //kerrlint:ignore kerrcompare Chain 返回子错误本身
+		t.Errorf("expected MultiError children in chain, got %v", chain)
+	}
+}
+
+func TestRootCause(t *testing.T) {
+	if RootCause(nil) != nil {
+		t.Errorf("expected nil")
+	}
+	if got := RootCause(NoData); got != NoData { //kerrlint:ignore kerrcompare RootCause 返回原实例
+		t.Errorf("expected error itself without cause, got %v", got)
+	}
+	if got := RootCause(SystemError.Wrap(fmt.Errorf("a: %w", io.EOF))); got != io.EOF {
+		t.Errorf("expected io.EOF, got %v", got)
+	}
+	if got := RootCause(errors.Join(fmt.Errorf("a: %w", os.ErrNotExist), io.EOF)); got != os.ErrNotExist {
+		t.Errorf("expected first branch root, got %v", got)
+	}
+	if got := RootCause(Join(DBError.Wrap(io.ErrUnexpectedEOF), NoData)); got != io.ErrUnexpectedEOF {
+		t.Errorf("expected root of first child, got %v", got)
+	}
+	if got := RootCause(&multiCause{}); got == nil {
+		t.Errorf("expected error without causes to be its own root")
+	}
+}
+
+func TestFindCodeAndAsKError(t *testing.T) {
+	detailed := ValidationFailed.WithField("field", "email")
+	err := SystemError.Wrap(errors.Join(io.EOF, fmt.Errorf("validate: %w", detailed)))
+
+	found, ok := FindCode(err, ValidationFailed.Code())
+	if !ok || found.Fields()["field"] != "email" {
+		t.Errorf("expected to find ValidationFailed with fields, got %v", found)
+	}
+	if _, ok := FindCode(err, NoData.Code()); ok {
+		t.Errorf("unexpected NoData")
+	}
+	if _, ok := FindCode(Join(NoData, io.EOF), NoData.Code()); !ok {
+		t.Errorf("expected to find code in MultiError")
+	}
+
+	ke, ok := AsKError(fmt.Errorf("x: %w", err))
+	if !ok || ke.Code() != SystemError.Code() {
+		t.Errorf("expected outermost KError, got %v", ke)
+	}
+	if ke, ok := AsKError(errors.Join(io.EOF, NoData)); !ok || ke != NoData { //kerrlint:ignore kerrcompare AsKError 返回子错误本身
+		t.Errorf("expected KError from joined errors, got %v", ke)
+	}
+	if _, ok := AsKError(io.EOF); ok {
+		t.Errorf("unexpected KError")
+	}
+}
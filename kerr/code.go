@@ -57,3 +57,17 @@ var (
 	// 6xxxx - 预留扩展错误码
 	// =====================
 )
+
+// 内置错误码自动注册，便于通过 Lookup 将错误码映射回规范错误实例
+func init() {
+	for _, err := range []Error{
+		Succ,
+		SystemError, ConfigError, TimeoutError, PanicError, InternalIOError,
+		Unauthorized, Forbidden, UserNotFound, PasswordError, TokenExpired, AccountLocked, QuotaExceeded,
+		InvalidState, OperationConflict, DependencyMissing, NoData, ValidationFailed,
+		DBError, CacheError, NetworkError, ThirdPartyError, MessageQueueError,
+		NameRegistered, NameNotRegistered, ResourcePoolEmpty, ServiceNotReady,
+	} {
+		MustRegister(err)
+	}
+}
@@ -0,0 +1,238 @@
+package kerr
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Detail 错误详情载荷，参照 google.rpc 错误详情模型，用于向调用方传递机器可读的信息
+// DetailType 作为 JSON 中 "@type" 的值，反序列化时据此还原具体类型
+type Detail interface {
+	DetailType() string
+}
+
+// FieldViolation 请求参数校验失败的字段，适用于 ValidationFailed
+type FieldViolation struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// DetailType 实现 Detail 接口
+func (FieldViolation) DetailType() string { return "FieldViolation" }
+
+// RetryInfo 建议的重试等待时间，JSON 中以 time.Duration 字符串表示，如 "1.5s"
+type RetryInfo struct {
+	Delay time.Duration `json:"delay"`
+}
+
+// DetailType 实现 Detail 接口
+func (RetryInfo) DetailType() string { return "RetryInfo" }
+
+// MarshalJSON 将 Delay 序列化为时长字符串
+func (r RetryInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Delay string `json:"delay"`
+	}{Delay: r.Delay.String()})
+}
+
+// UnmarshalJSON 解析时长字符串形式的 Delay
+func (r *RetryInfo) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Delay string `json:"delay"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	delay, err := time.ParseDuration(v.Delay)
+	if err != nil {
+		return err
+	}
+	r.Delay = delay
+	return nil
+}
+
+// QuotaFailure 超出的配额，适用于 QuotaExceeded
+type QuotaFailure struct {
+	Subject string `json:"subject"`
+	Limit   int64  `json:"limit"`
+}
+
+// DetailType 实现 Detail 接口
+func (QuotaFailure) DetailType() string { return "QuotaFailure" }
+
+// ResourceInfo 访问的资源信息，适用于 NoData、Forbidden 等
+type ResourceInfo struct {
+	ResourceType string `json:"resource_type"`
+	ResourceName string `json:"resource_name"`
+	Owner        string `json:"owner,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// DetailType 实现 Detail 接口
+func (ResourceInfo) DetailType() string { return "ResourceInfo" }
+
+// RawDetail 未注册类型的详情，保留原始 JSON，再次序列化时原样输出
+type RawDetail struct {
+	Type  string
+	Value json.RawMessage
+}
+
+// DetailType 实现 Detail 接口
+func (d RawDetail) DetailType() string { return d.Type }
+
+// detailTypes 详情类型注册表，"@type" -> 解码函数
+var detailTypes = struct {
+	mu    sync.RWMutex
+	types map[string]func(data []byte) (Detail, error)
+}{
+	types: map[string]func(data []byte) (Detail, error){
+		"FieldViolation": decodeDetail[FieldViolation],
+		"RetryInfo":      decodeDetail[RetryInfo],
+		"QuotaFailure":   decodeDetail[QuotaFailure],
+		"ResourceInfo":   decodeDetail[ResourceInfo],
+	},
+}
+
+// decodeDetail 将 JSON 解码为值类型 T 的详情
+func decodeDetail[T Detail](data []byte) (Detail, error) {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// RegisterDetail 注册自定义详情类型，使其可以从 JSON 还原，如 kerr.RegisterDetail[OrderInfo]()
+// T 须为值类型，DetailsOf[T] 按值类型匹配
+func RegisterDetail[T Detail]() {
+	var zero T
+	detailTypes.mu.Lock()
+	defer detailTypes.mu.Unlock()
+	detailTypes.types[zero.DetailType()] = decodeDetail[T]
+}
+
+// newDetail 按 "@type" 解析详情，未注册的类型还原为 RawDetail
+func newDetail(data json.RawMessage) (Detail, error) {
+	var head struct {
+		Type string `json:"@type"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+	detailTypes.mu.RLock()
+	decode, ok := detailTypes.types[head.Type]
+	detailTypes.mu.RUnlock()
+	if !ok {
+		return RawDetail{Type: head.Type, Value: data}, nil
+	}
+	d, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode detail %s: %w", head.Type, err)
+	}
+	return d, nil
+}
+
+// detailList 详情列表的 JSON 表示，每个详情带有 "@type" 成员
+type detailList []Detail
+
+// MarshalJSON 序列化详情，并在每个对象中写入 "@type"
+func (l detailList) MarshalJSON() ([]byte, error) {
+	out := make([]json.RawMessage, 0, len(l))
+	for _, d := range l {
+		if raw, ok := d.(RawDetail); ok {
+			out = append(out, raw.Value)
+			continue
+		}
+		data, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("detail %s must marshal to a JSON object: %w", d.DetailType(), err)
+		}
+		if m == nil {
+			m = make(map[string]json.RawMessage, 1)
+		}
+		m["@type"], _ = json.Marshal(d.DetailType())
+		data, err = json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, data)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 按 "@type" 还原详情
+func (l *detailList) UnmarshalJSON(data []byte) error {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return err
+	}
+	list := make(detailList, 0, len(raws))
+	for _, raw := range raws {
+		d, err := newDetail(raw)
+		if err != nil {
+			return err
+		}
+		list = append(list, d)
+	}
+	*l = list
+	return nil
+}
+
+// MarshalDetails 序列化详情列表，每个详情带有 "@type" 成员，便于通过其他协议传递详情
+func MarshalDetails(details []Detail) ([]byte, error) {
+	return detailList(details).MarshalJSON()
+}
+
+// UnmarshalDetails 还原 MarshalDetails 的输出，未注册的类型还原为 RawDetail
+func UnmarshalDetails(data []byte) ([]Detail, error) {
+	var list detailList
+	if err := list.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// appendDetails 返回追加详情后的新切片，不修改原切片
+func appendDetails(own []Detail, details []Detail) []Detail {
+	out := make([]Detail, 0, len(own)+len(details))
+	out = append(out, own...)
+	for _, d := range details {
+		if d != nil {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// DetailsOf 沿错误链（包括 MultiError 的子错误）收集类型为 T 的详情，外层在前，不包含层边界私有 cause 中的详情
+//
+//	for _, v := range kerr.DetailsOf[kerr.FieldViolation](err) {
+//		fmt.Println(v.Field, v.Reason)
+//	}
+func DetailsOf[T Detail](err error) []T {
+	var out []T
+	for _, d := range allDetails(err) {
+		if v, ok := d.(T); ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// allDetails 沿错误链收集所有详情，外层在前，不越过层边界保留的私有 cause
+func allDetails(err error) []Detail {
+	var out []Detail
+	walkChain(err, func(e error) bool {
+		if d, ok := e.(interface{ Details() []Detail }); ok {
+			out = append(out, d.Details()...)
+		}
+		return true
+	})
+	return out
+}
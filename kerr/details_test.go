@@ -0,0 +1,145 @@
+package kerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// orderInfo 测试用的自定义详情类型
+type orderInfo struct {
+	OrderID string `json:"order_id"`
+}
+
+func (orderInfo) DetailType() string { return "test.OrderInfo" }
+
+func TestWithDetailsImmutable(t *testing.T) {
+	base := ValidationFailed.WithDetails(FieldViolation{Field: "email", Reason: "invalid format"})
+	more := base.WithDetails(FieldViolation{Field: "age", Reason: "must be positive"})
+
+	if len(ValidationFailed.Details()) != 0 {
+		t.Fatalf("WithDetails must not modify the sentinel")
+	}
+	if len(base.Details()) != 1 || len(more.Details()) != 2 {
+		t.Fatalf("unexpected details: %v / %v", base.Details(), more.Details())
+	}
+	if !errors.Is(more, ValidationFailed) {
+		t.Errorf("details must not affect errors.Is")
+	}
+	if base.WithDetails() != base {
+		t.Errorf("WithDetails without details should return the receiver")
+	}
+}
+
+func TestDetailsOf(t *testing.T) {
+	inner := QuotaExceeded.WithDetails(
+		QuotaFailure{Subject: "user:1", Limit: 100},
+		RetryInfo{Delay: 30 * time.Second},
+	)
+	outer := SystemError.Wrap(fmt.Errorf("call quota: %w", inner)).
+		WithDetails(ResourceInfo{ResourceType: "quota", ResourceName: "api-calls"})
+	multi := Join(outer, ValidationFailed.WithDetails(FieldViolation{Field: "name", Reason: "required"}))
+
+	if got := DetailsOf[QuotaFailure](outer); !reflect.DeepEqual(got, []QuotaFailure{{Subject: "user:1", Limit: 100}}) {
+		t.Errorf("unexpected quota failures: %v", got)
+	}
+	if got := DetailsOf[RetryInfo](multi); len(got) != 1 || got[0].Delay != 30*time.Second {
+		t.Errorf("unexpected retry info: %v", got)
+	}
+	if got := DetailsOf[FieldViolation](multi); len(got) != 1 || got[0].Field != "name" {
+		t.Errorf("expected field violation from a joined error, got %v", got)
+	}
+	if got := DetailsOf[FieldViolation](errors.New("plain")); got != nil {
+		t.Errorf("expected no details, got %v", got)
+	}
+}
+
+func TestDetailsJSONRoundTrip(t *testing.T) {
+	RegisterDetail[orderInfo]()
+
+	err := QuotaExceeded.Wrap(NoData.WithDetails(orderInfo{OrderID: "A001"})).WithDetails(
+		QuotaFailure{Subject: "user:1", Limit: 100},
+		RetryInfo{Delay: 1500 * time.Millisecond},
+	)
+	data, jsonErr := json.Marshal(err)
+	if jsonErr != nil {
+		t.Fatalf("Marshal failed: %v", jsonErr)
+	}
+	for _, want := range []string{`"@type":"QuotaFailure"`, `"delay":"1.5s"`, `"@type":"test.OrderInfo"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %s in %s", want, data)
+		}
+	}
+
+	restored, jsonErr := FromJSON(data)
+	if jsonErr != nil {
+		t.Fatalf("FromJSON failed: %v", jsonErr)
+	}
+	if !reflect.DeepEqual(restored.Details(), err.Details()) {
+		t.Errorf("details not restored: %v", restored.Details())
+	}
+	if got := DetailsOf[orderInfo](restored); len(got) != 1 || got[0].OrderID != "A001" {
+		t.Errorf("expected custom detail from nested cause, got %v", got)
+	}
+
+	// 未注册的类型原样保留
+	remote := []byte(`{"code":30004,"message":"bad","details":[{"@type":"remote.Unknown","x":1}]}`)
+	restored, jsonErr = FromJSON(remote)
+	if jsonErr != nil {
+		t.Fatalf("FromJSON failed: %v", jsonErr)
+	}
+	raw := DetailsOf[RawDetail](restored)
+	if len(raw) != 1 || raw[0].DetailType() != "remote.Unknown" {
+		t.Fatalf("expected raw detail, got %v", restored.Details())
+	}
+	if !strings.Contains(restored.ToJSON(), `{"@type":"remote.Unknown","x":1}`) {
+		t.Errorf("raw detail must be marshaled unchanged: %s", restored.ToJSON())
+	}
+
+	if _, jsonErr := FromJSON([]byte(`{"code":1,"details":[{"@type":"RetryInfo","delay":"soon"}]}`)); jsonErr == nil {
+		t.Errorf("expected error for invalid delay")
+	}
+}
+
+func TestDetailsMultiErrorAndProblem(t *testing.T) {
+	multi := Join(ValidationFailed, NoData).WithDetails(FieldViolation{Field: "id", Reason: "unknown"})
+	if !strings.Contains(multi.ToJSON(), `"details":[{"@type":"FieldViolation","field":"id","reason":"unknown"}]`) {
+		t.Errorf("unexpected MultiError JSON: %s", multi.ToJSON())
+	}
+	if !strings.Contains(fmt.Sprintf("%+v", multi), "detail: FieldViolation{Field:id Reason:unknown}") {
+		t.Errorf("expected details in %%+v output")
+	}
+
+	data, _ := json.Marshal(ToProblem(multi, ProblemOptions{}))
+	parsed, err := ParseProblem(data)
+	if err != nil {
+		t.Fatalf("ParseProblem failed: %v", err)
+	}
+	if got := DetailsOf[FieldViolation](parsed); len(got) != 1 || got[0].Field != "id" {
+		t.Errorf("expected details restored from problem, got %v", parsed.Details())
+	}
+	if _, ok := parsed.Fields()["details"]; ok {
+		t.Errorf("details must not be duplicated into fields")
+	}
+}
+
+func TestDetailsHTTP(t *testing.T) {
+	setExposure(t, Public)
+	err := ValidationFailed.WithDetails(FieldViolation{Field: "email", Reason: "invalid format"})
+
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, NoData.Wrap(err))
+	if !strings.Contains(rec.Body.String(), `"details":[{"@type":"FieldViolation","field":"email","reason":"invalid format"}]`) {
+		t.Errorf("expected details in the public response body, got %s", rec.Body.String())
+	}
+
+	remote := FromHTTPResponse(rec.Result())
+	if got := DetailsOf[FieldViolation](remote); len(got) != 1 || got[0].Field != "email" {
+		t.Errorf("expected details decoded from the response, got %v", remote.Details())
+	}
+}
@@ -1,51 +1,124 @@
 package kerr
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"runtime"
+	"sort"
 	"strings"
 )
 
 // Error 定义了错误接口
+// 解包通过 errors.Unwrap、errors.Is/As 完成：KError 实现 Unwrap() error，MultiError 实现 Unwrap() []error
 type Error interface {
 	error
 	Code() int
 	Display() string
+	DisplayIn(locale string) string
 	Wrap(err error) Error
-	Unwrap() error
 	Stack() string
 	Is(target error) bool
 	WithStack(skip ...int) Error
 	WithDisplay(display string) Error
+	WithField(key string, val any) Error
+	WithFields(fields map[string]any) Error
+	Fields() map[string]any
+	WithMessage(format string, args ...any) Error
+	WithDetails(details ...Detail) Error
+	Details() []Detail
 	ToJSON() string
 }
 
 // New 创建一个新的错误实例
+// 错误码按 SetStackCapture 配置需要自动收集调用栈时，同时收集调用栈；包初始化期间创建的哨兵错误不收集
 func New(code int, msg string) *KError {
 	return &KError{
 		code:    code,
 		msg:     msg,
 		display: msg, // 默认 display = msg
+		stack:   autoStack(code),
 	}
 }
 
+// Newf 创建一个新的错误实例，错误信息按 format 格式化
+// 格式化参数会保留，可在多语言显示信息模板中通过 .Args 引用
+func Newf(code int, format string, args ...any) *KError {
+	msg := fmt.Sprintf(format, args...)
+	return &KError{
+		code:    code,
+		msg:     msg,
+		display: msg,
+		args:    args,
+		stack:   autoStack(code),
+	}
+}
+
+// Wrapf 为错误附加格式化的上下文信息，保持原错误的错误码和显示信息
+// err 为 nil 时返回 nil；err 包装了 kerr 错误（如 fmt.Errorf("load: %w", kerr.NoData)）时，
+// 沿用错误链上第一个 kerr 错误的错误码和显示信息并保留外层包装；错误链上没有 kerr 错误时，先包装为 SystemError
+func Wrapf(err error, format string, args ...any) Error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(Error); ok {
+		return e.WithMessage(format, args...)
+	}
+	var inner Error
+	if !errors.As(err, &inner) {
+		return SystemError.Wrap(err).WithMessage(format, args...)
+	}
+	e := New(inner.Code(), fmt.Sprintf(format, args...))
+	e.display = inner.Display()
+	e.cause = err
+	return e
+}
+
 // KError 是 Error 的默认实现
 type KError struct {
 	code    int
 	msg     string
 	display string
 	cause   error
-	stack   []uintptr
+	stack   *stack
+	frames  []string       // 远端错误的堆栈帧，反序列化得到，仅作展示
+	fields  map[string]any // 结构化字段，仅在新实例中修改
+	notes   []string       // 附加的上下文信息，由外到内排列
+	args    []any          // Newf 的格式化参数，用于渲染多语言显示信息
+	details []Detail       // 机器可读的错误详情，仅在新实例中追加
+	// 远端错误的指纹，反序列化得到
+	fingerprint string
+	// displaySet 显示信息已由 WithDisplay 设置；displayOverride 为实例级覆盖的显示信息，优先于多语言目录
+	displaySet      bool
+	displayOverride bool
 }
 
-// Error 返回错误信息
+// clone 浅拷贝当前实例，用于派生不可变的新实例
+func (e *KError) clone() *KError {
+	c := *e
+	return &c
+}
+
+// Error 返回未脱敏的错误信息，不受暴露级别影响
+// 脱敏在输出时进行：%v、%+v、ToJSON、MarshalJSON、WriteHTTP 及 ToProblem 按脱敏规则处理，klog 按 %v 输出错误
 func (e *KError) Error() string {
-	if e.cause != nil {
-		return fmt.Sprintf("%s: %v", e.msg, e.cause)
+	return e.rawText()
+}
+
+// rawText 返回未脱敏的错误信息，不包含层边界保留的私有 cause
+func (e *KError) rawText() string {
+	if _, private := e.cause.(*privateError); e.cause != nil && !private {
+		return e.message() + ": " + rawTextOf(e.cause)
 	}
-	return e.msg
+	return e.message()
+}
+
+// message 返回带上下文信息的错误信息（不含 cause），形如 "note2: note1: msg"
+func (e *KError) message() string {
+	if len(e.notes) == 0 {
+		return e.msg
+	}
+	return strings.Join(e.notes, ": ") + ": " + e.msg
 }
 
 // Code 返回错误码
@@ -62,30 +135,109 @@ func (e *KError) Display() string {
 	return e.msg
 }
 
+// DisplayIn 返回指定语言的显示信息
+// 按语言回退链查找消息目录，均未配置时返回 Display；实例级覆盖的显示信息优先于消息目录
+func (e *KError) DisplayIn(locale string) string {
+	if e.displayOverride {
+		return e.Display()
+	}
+	msg, ok := lookupMessage(locale, e.code)
+	if !ok {
+		return e.Display()
+	}
+	return renderMessage(msg, e.Fields(), e.args)
+}
+
 // WithDisplay 设置错误显示信息
+// New 之后首次设置的是错误码的默认显示信息，多语言目录优先；
+// 对已设置显示信息的实例（如已定义的错误码）再次设置视为实例级覆盖，优先于多语言目录
 func (e *KError) WithDisplay(display string) Error {
 	// 复制原实例的所有字段，仅修改 display
-	return &KError{
-		code:    e.code,
-		msg:     e.msg,
-		display: display, // 新值
-		cause:   e.cause,
-		stack:   e.stack,
+	c := e.clone()
+	c.display = display
+	c.displayOverride = e.displaySet
+	c.displaySet = true
+	return c
+}
+
+// WithMessage 附加格式化的上下文信息，返回新实例
+// 错误码、显示信息和 cause 保持不变，因此 Is 判断不受影响
+func (e *KError) WithMessage(format string, args ...any) Error {
+	c := e.clone()
+	c.notes = make([]string, 0, len(e.notes)+1)
+	c.notes = append(c.notes, fmt.Sprintf(format, args...))
+	c.notes = append(c.notes, e.notes...)
+	return c
+}
+
+// WithField 附加结构化字段，返回新实例
+func (e *KError) WithField(key string, val any) Error {
+	return e.WithFields(map[string]any{key: val})
+}
+
+// WithFields 批量附加结构化字段，返回新实例，同名字段覆盖旧值
+func (e *KError) WithFields(fields map[string]any) Error {
+	if len(fields) == 0 {
+		return e
 	}
+	c := e.clone()
+	c.fields = make(map[string]any, len(e.fields)+len(fields))
+	for k, v := range e.fields {
+		c.fields[k] = v
+	}
+	for k, v := range fields {
+		c.fields[k] = v
+	}
+	return c
+}
+
+// RedactedFields 返回脱敏后的 Fields，用于日志等输出
+func (e *KError) RedactedFields() map[string]any {
+	return RedactFields(e.Fields())
+}
+
+// Fields 返回沿错误链合并后的结构化字段，外层字段覆盖内层同名字段
+func (e *KError) Fields() map[string]any {
+	merged := make(map[string]any)
+	var inner interface{ Fields() map[string]any }
+	if e.cause != nil && errors.As(e.cause, &inner) {
+		for k, v := range inner.Fields() {
+			merged[k] = v
+		}
+	}
+	for k, v := range e.fields {
+		merged[k] = v
+	}
+	return merged
+}
+
+// WithDetails 附加错误详情，返回新实例
+func (e *KError) WithDetails(details ...Detail) Error {
+	if len(details) == 0 {
+		return e
+	}
+	c := e.clone()
+	c.details = appendDetails(e.details, details)
+	return c
+}
+
+// Details 返回当前错误附加的详情，不包含 cause 中的详情，沿错误链查找请使用 DetailsOf
+func (e *KError) Details() []Detail {
+	return append([]Detail(nil), e.details...)
 }
 
 // Wrap 包装错误，返回新实例
+// 尚无调用栈且错误码按 SetStackCapture 配置需要自动收集时，同时收集调用栈
 func (e *KError) Wrap(err error) Error {
 	if err == nil {
 		return e
 	}
-	return &KError{
-		code:    e.code,
-		msg:     e.msg,
-		cause:   err,
-		display: e.display,
-		stack:   e.stack,
+	c := e.clone()
+	c.cause = err
+	if c.stack == nil {
+		c.stack = autoStack(c.code)
 	}
+	return c
 }
 
 // Unwrap 返回被包装的错误
@@ -96,18 +248,12 @@ func (e *KError) Unwrap() error {
 // Stack 返回调用栈
 func (e *KError) Stack() string {
 	if e.stack == nil {
-		return ""
-	}
-	var b strings.Builder
-	frames := runtime.CallersFrames(e.stack)
-	for {
-		frame, more := frames.Next()
-		b.WriteString(fmt.Sprintf("%s:%d %s\n", frame.File, frame.Line, frame.Function))
-		if !more {
-			break
+		if len(e.frames) > 0 {
+			return strings.Join(e.frames, "\n") + "\n"
 		}
+		return ""
 	}
-	return b.String()
+	return e.stack.String()
 }
 
 // Is 判断相同错误码的错误
@@ -124,91 +270,199 @@ func (e *KError) Is(target error) bool {
 	return false
 }
 
+// callStack 返回收集的调用栈，用于计算指纹
+func (e *KError) callStack() *stack {
+	return e.stack
+}
+
 // WithStack 收集调用栈，返回新实例
 func (e *KError) WithStack(skip ...int) Error {
 	if e.stack != nil {
 		return e // 已存在堆栈，直接返回原实例（不可变，安全）
 	}
 	// 新建堆栈，返回新实例
-	stack := make([]uintptr, 32)
-	skipCount := 2 // 默认跳过 WithStack 和直接调用者
+	skipCount := 0 // 默认从 WithStack 的调用者开始
 	if len(skip) > 0 {
 		skipCount += skip[0]
 	}
-	n := runtime.Callers(skipCount, stack)
-	return &KError{
-		code:    e.code,
-		msg:     e.msg,
-		display: e.display,
-		cause:   e.cause,
-		stack:   stack[:n], // 新堆栈仅在新实例中
-	}
+	c := e.clone()
+	c.stack = captureStack(skipCount) // 新堆栈仅在新实例中
+	return c
 }
 
-// Format 支持 fmt.Printf("%+v", err) 打印堆栈
+// Format 支持 fmt.Printf("%+v", err) 打印堆栈，开启脱敏时按脱敏规则处理
+// %v、%s 与 Error() 相同，%+v 输出错误信息、字段、详情、堆栈和 cause，不受暴露级别影响
 func (e *KError) Format(f fmt.State, c rune) {
+	o := fullRender()
 	switch c {
 	case 'v':
 		if f.Flag('+') {
-			fmt.Fprintf(f, "%s (code=%d, display=%q)\n", e.msg, e.code, e.display)
-			if e.stack != nil {
-				fmt.Fprint(f, e.Stack())
-			}
-			if e.cause != nil {
-				fmt.Fprintf(f, "Caused by: %+v\n", e.cause)
-			}
+			fmt.Fprint(f, e.verbose(o))
 			return
 		}
 		fallthrough
 	case 's':
-		fmt.Fprint(f, e.Error())
+		fmt.Fprint(f, e.text(o))
 	default:
-		fmt.Fprint(f, e.Error())
+		fmt.Fprint(f, e.text(o))
+	}
+}
+
+// text 返回 %s、%v 的输出
+func (e *KError) text(o renderOptions) string {
+	if o.exposure == Public {
+		return e.Display()
+	}
+	return redactText(e.rawText(), o.redact)
+}
+
+// verbose 返回 %+v 的输出：错误信息、字段、详情、堆栈及 cause
+func (e *KError) verbose(o renderOptions) string {
+	if o.exposure == Public {
+		return fmt.Sprintf("%s (code=%d)\n", e.Display(), e.code)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (code=%d, display=%q)\n", redactText(e.message(), o.redact), e.code, e.display)
+	if len(e.fields) > 0 {
+		fmt.Fprintf(&b, "fields: %s\n", formatFields(redactFields(e.fields, o.redact)))
+	}
+	for _, d := range e.details {
+		fmt.Fprintf(&b, "detail: %s%+v\n", d.DetailType(), d)
+	}
+	if o.exposure == Debug && (e.stack != nil || len(e.frames) > 0) {
+		b.WriteString(e.Stack())
 	}
+	if e.cause != nil {
+		fmt.Fprintf(&b, "Caused by: %s\n", verboseOf(e.cause, o))
+	}
+	return b.String()
 }
 
-// MarshalJSON 支持 JSON 序列化
+// kerrorJSON KError 的 JSON 结构
+type kerrorJSON struct {
+	Code        int             `json:"code"`
+	Fingerprint string          `json:"fingerprint,omitempty"`
+	Msg         string          `json:"message"`
+	Notes       []string        `json:"annotations,omitempty"`
+	Display     string          `json:"display,omitempty"`
+	Cause       json.RawMessage `json:"cause,omitempty"` // KError 为嵌套对象，其他错误为字符串
+	Fields      map[string]any  `json:"fields,omitempty"`
+	Details     detailList      `json:"details,omitempty"`
+	Stack       []string        `json:"stack,omitempty"`
+}
+
+// MarshalJSON 支持 JSON 序列化，开启脱敏时错误信息和字段按脱敏规则处理
+// 输出完整信息用于日志和服务间传递，可由 FromJSON 还原，不受暴露级别影响；离开服务的输出请使用 WriteHTTP 等
 func (e *KError) MarshalJSON() ([]byte, error) {
+	return e.marshalJSON(fullRender())
+}
+
+// marshalJSON 按输出选项序列化为 JSON
+func (e *KError) marshalJSON(o renderOptions) ([]byte, error) {
+	if o.exposure == Public {
+		return json.Marshal(kerrorJSON{Code: e.code, Msg: e.Display(), Display: e.Display()})
+	}
+
 	// 递归处理底层错误的 JSON 序列化
-	var causeJSON interface{}
+	var causeJSON json.RawMessage
 	if e.cause != nil {
-		if kerr, ok := e.cause.(*KError); ok {
-			// 若底层是 KError，直接复用其序列化结果
-			causeJSON, _ = kerr.MarshalJSON()
-		} else {
-			// 其他错误类型，序列化其错误信息
-			causeJSON = e.cause.Error()
+		var err error
+		if causeJSON, err = marshalCause(e.cause, o); err != nil {
+			return nil, err
 		}
 	}
 
-	// 堆栈处理保持不变
-	stackTrace := []string{}
-	for _, pc := range e.stack {
-		if fn := runtime.FuncForPC(pc); fn != nil {
-			file, line := fn.FileLine(pc)
-			stackTrace = append(stackTrace, fmt.Sprintf("%s:%d", file, line))
+	var stackTrace []string
+	if o.exposure == Debug {
+		stackTrace = e.frames
+		if e.stack != nil {
+			stackTrace = e.stack.lines()
 		}
 	}
 
-	return json.Marshal(struct {
-		Code    int         `json:"code"`
-		Msg     string      `json:"message"`
-		Display string      `json:"display,omitempty"`
-		Cause   interface{} `json:"cause,omitempty"` // 支持嵌套序列化
-		Stack   []string    `json:"stack,omitempty"`
-	}{
-		Code:    e.code,
-		Msg:     e.msg,
-		Display: e.display,
-		Cause:   causeJSON,
-		Stack:   stackTrace,
+	return json.Marshal(kerrorJSON{
+		Code:        e.code,
+		Fingerprint: e.Fingerprint(),
+		Msg:         redactText(e.msg, o.redact),
+		Notes:       redactNotes(e.notes, o.redact),
+		Display:     e.display,
+		Cause:       causeJSON,
+		Fields:      redactFields(e.fields, o.redact),
+		Details:     e.details,
+		Stack:       stackTrace,
 	})
 }
 
+// UnmarshalJSON 支持 JSON 反序列化，可还原 MarshalJSON 的输出
+// 嵌套的 cause 对象还原为 *KError，字符串 cause 还原为普通 error，
+// 远端堆栈作为不透明的帧保留，可通过 Stack 查看
+func (e *KError) UnmarshalJSON(data []byte) error {
+	var v kerrorJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var cause error
+	raw := bytes.TrimSpace(v.Cause)
+	switch {
+	case len(raw) == 0 || bytes.Equal(raw, []byte("null")):
+	case raw[0] == '{':
+		nested := &KError{}
+		if err := nested.UnmarshalJSON(raw); err != nil {
+			return err
+		}
+		cause = nested
+	default:
+		var msg string
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return err
+		}
+		cause = errors.New(msg)
+	}
+
+	*e = KError{
+		code:        v.Code,
+		msg:         v.Msg,
+		display:     v.Display,
+		cause:       cause,
+		frames:      v.Stack,
+		fields:      v.Fields,
+		notes:       v.Notes,
+		details:     v.Details,
+		fingerprint: v.Fingerprint,
+	}
+	return nil
+}
+
+// FromJSON 从 JSON 数据还原 KError，用于跨服务传递错误
+// 还原后的错误可通过 errors.Is 与本地错误码常量比较
+func FromJSON(data []byte) (*KError, error) {
+	e := &KError{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ToJSON 返回 JSON 字符串，内容与 MarshalJSON 相同
 func (e *KError) ToJSON() string {
-	b, err := json.Marshal(e)
+	b, err := e.marshalJSON(fullRender())
 	if err != nil {
-		return fmt.Sprintf(`{"code":%d,"message":"%s"}`, e.code, e.msg)
+		return fmt.Sprintf(`{"code":%d,"message":%q}`, e.code, e.Display())
 	}
 	return string(b)
 }
+
+// formatFields 按键排序格式化结构化字段，形如 k1=v1 k2=v2
+func formatFields(fields map[string]any) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	return strings.Join(pairs, " ")
+}
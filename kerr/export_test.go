@@ -0,0 +1,64 @@
+package kerr
+
+import (
+	"maps"
+	"slices"
+)
+
+// unregister 移除测试注册的错误码、属性及注册时设置的 HTTP 状态码，用于 t.Cleanup 隔离全局注册表
+func unregister(codes ...int) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for _, code := range codes {
+		if registry.attrs[code].httpStatus != 0 {
+			httpMapping.mu.Lock()
+			delete(httpMapping.codes, code)
+			httpMapping.mu.Unlock()
+		}
+		delete(registry.codes, code)
+		delete(registry.attrs, code)
+	}
+}
+
+// snapshotCatalogs 保存多语言目录、回退链和默认语言，返回恢复函数，用于 t.Cleanup 隔离全局目录
+func snapshotCatalogs() (restore func()) {
+	catalogs.mu.RLock()
+	messages := make(map[string]map[int]string, len(catalogs.messages))
+	for locale, catalog := range catalogs.messages {
+		messages[locale] = maps.Clone(catalog)
+	}
+	fallbacks := maps.Clone(catalogs.fallbacks)
+	defaultLocale := catalogs.defaultLocale
+	catalogs.mu.RUnlock()
+
+	return func() {
+		catalogs.mu.Lock()
+		defer catalogs.mu.Unlock()
+		catalogs.messages = messages
+		catalogs.fallbacks = fallbacks
+		catalogs.defaultLocale = defaultLocale
+	}
+}
+
+// snapshotHTTPMapping 保存 HTTP 状态码映射表，返回恢复函数，用于 t.Cleanup 隔离全局映射
+func snapshotHTTPMapping() (restore func()) {
+	httpMapping.mu.RLock()
+	codes := maps.Clone(httpMapping.codes)
+	ranges := slices.Clone(httpMapping.ranges)
+	httpMapping.mu.RUnlock()
+
+	return func() {
+		httpMapping.mu.Lock()
+		defer httpMapping.mu.Unlock()
+		httpMapping.codes = codes
+		httpMapping.ranges = ranges
+	}
+}
+
+// snapshotStackPolicy 保存调用栈收集策略，返回恢复函数，用于 t.Cleanup 隔离全局策略
+func snapshotStackPolicy() (restore func()) {
+	config := stackPolicy.config.Load()
+	return func() {
+		stackPolicy.config.Store(config)
+	}
+}
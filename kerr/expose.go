@@ -0,0 +1,254 @@
+package kerr
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Exposure 错误信息的暴露级别，决定离开服务的输出内容：WriteHTTP、ToProblem、WriteProblem 及 kgrpc 的 status
+// Error()、Format 和 MarshalJSON、ToJSON 用于日志和服务间传递，不受暴露级别影响，始终输出完整信息（按脱敏规则处理）；
+// 需要按指定级别格式化时使用 Expose
+type Exposure int
+
+const (
+	// Public 仅暴露错误码和显示信息，用于生产环境
+	Public Exposure = iota
+	// Internal 额外暴露错误信息、cause 和结构化字段，用于预发、测试环境
+	Internal
+	// Debug 额外暴露调用栈，用于开发环境
+	Debug
+)
+
+// ExposureEnv 设置默认暴露级别的环境变量，取值见 ParseExposure，未设置或无法识别时为 Public
+const ExposureEnv = "KERR_EXPOSURE"
+
+// exposure 当前的暴露级别
+var exposure = struct {
+	mu    sync.RWMutex
+	level Exposure
+}{
+	level: exposureFromEnv(),
+}
+
+// exposureFromEnv 从环境变量读取暴露级别
+func exposureFromEnv() Exposure {
+	x, err := ParseExposure(os.Getenv(ExposureEnv))
+	if err != nil {
+		return Public
+	}
+	return x
+}
+
+// String 返回暴露级别的名称
+func (x Exposure) String() string {
+	switch x {
+	case Public:
+		return "public"
+	case Internal:
+		return "internal"
+	case Debug:
+		return "debug"
+	}
+	return fmt.Sprintf("Exposure(%d)", int(x))
+}
+
+// ParseExposure 解析暴露级别，不区分大小写，也接受环境名称：
+// public、prod、production 为 Public；internal、staging、test 为 Internal；debug、dev、development、local 为 Debug
+func ParseExposure(s string) (Exposure, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "public", "prod", "production":
+		return Public, nil
+	case "internal", "staging", "test":
+		return Internal, nil
+	case "debug", "dev", "development", "local":
+		return Debug, nil
+	}
+	return Public, ConfigError.Wrap(fmt.Errorf("unknown exposure %q", s))
+}
+
+// SetExposure 设置暴露级别，覆盖环境变量的设置
+func SetExposure(x Exposure) {
+	exposure.mu.Lock()
+	defer exposure.mu.Unlock()
+	exposure.level = x
+}
+
+// CurrentExposure 返回当前的暴露级别
+func CurrentExposure() Exposure {
+	exposure.mu.RLock()
+	defer exposure.mu.RUnlock()
+	return exposure.level
+}
+
+// publicFields 暴露级别为 Public 时允许离开服务的结构化字段
+var publicFields = struct {
+	mu   sync.RWMutex
+	keys map[string]bool
+}{}
+
+// SetPublicFields 设置暴露级别为 Public 时允许输出到 WriteHTTP、ToProblem 及 kgrpc status 的结构化字段，替换之前的设置
+// 默认 Public 级别不输出字段，Internal、Debug 级别输出全部字段：
+//
+//	kerr.SetPublicFields("order_id", "field")
+func SetPublicFields(keys ...string) {
+	m := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		m[k] = true
+	}
+	publicFields.mu.Lock()
+	defer publicFields.mu.Unlock()
+	publicFields.keys = m
+}
+
+// ExposedFields 返回按当前暴露级别可以离开服务的结构化字段，已按脱敏规则处理
+// Public 级别仅包含 SetPublicFields 允许的字段，Internal、Debug 级别包含全部字段；用于自定义的对外渲染
+func ExposedFields(e Error) map[string]any {
+	fields := RedactFields(e.Fields())
+	if CurrentExposure() != Public {
+		return fields
+	}
+	publicFields.mu.RLock()
+	defer publicFields.mu.RUnlock()
+	for k := range fields {
+		if !publicFields.keys[k] {
+			delete(fields, k)
+		}
+	}
+	return fields
+}
+
+// renderOptions 错误的输出选项
+type renderOptions struct {
+	redact   bool     // 是否脱敏
+	exposure Exposure // 暴露级别
+}
+
+// defaultRender 返回按当前脱敏和暴露级别配置的输出选项，用于离开服务的输出
+func defaultRender() renderOptions {
+	return renderOptions{redact: redactionEnabled(), exposure: CurrentExposure()}
+}
+
+// fullRender 返回输出完整信息的选项，用于 Format 和 MarshalJSON，仍按脱敏配置处理
+func fullRender() renderOptions {
+	return renderOptions{redact: redactionEnabled(), exposure: Debug}
+}
+
+// renderable 可按输出选项渲染的错误，由 KError、MultiError 等实现
+type renderable interface {
+	rawText() string                             // 未脱敏的错误信息
+	text(o renderOptions) string                 // %s、%v 的输出
+	verbose(o renderOptions) string              // %+v 的输出
+	marshalJSON(o renderOptions) ([]byte, error) // JSON 序列化
+}
+
+// rawTextOf 返回错误未脱敏的错误信息，非 kerr 错误返回 Error()
+func rawTextOf(err error) string {
+	if r, ok := err.(renderable); ok {
+		return r.rawText()
+	}
+	return err.Error()
+}
+
+// renderableOf 返回可渲染的错误，Public 级别下非 kerr 错误按 Translate 转换，以输出对应的显示信息
+func renderableOf(err error, o renderOptions) (renderable, bool) {
+	if r, ok := err.(renderable); ok {
+		return r, true
+	}
+	if o.exposure == Public {
+		r, ok := Translate(err).(renderable)
+		return r, ok
+	}
+	return nil, false
+}
+
+// textOf 返回错误 %s、%v 的输出
+func textOf(err error, o renderOptions) string {
+	if r, ok := renderableOf(err, o); ok {
+		return r.text(o)
+	}
+	return redactText(err.Error(), o.redact)
+}
+
+// verboseOf 返回错误 %+v 的输出，非 kerr 错误仅在 Debug 级别使用其 %+v 输出
+func verboseOf(err error, o renderOptions) string {
+	if r, ok := renderableOf(err, o); ok {
+		return r.verbose(o)
+	}
+	if o.exposure == Debug {
+		return redactText(fmt.Sprintf("%+v", err), o.redact)
+	}
+	return redactText(err.Error(), o.redact)
+}
+
+// marshalOf 按输出选项序列化错误，非 kerr 错误使用自身的 MarshalJSON 或序列化为 {"message": ...}
+func marshalOf(err error, o renderOptions) ([]byte, error) {
+	if r, ok := renderableOf(err, o); ok {
+		return r.marshalJSON(o)
+	}
+	if m, ok := err.(json.Marshaler); ok {
+		return m.MarshalJSON()
+	}
+	return json.Marshal(struct {
+		Msg string `json:"message"`
+	}{Msg: redactText(err.Error(), o.redact)})
+}
+
+// marshalCause 序列化 KError 的 cause，KError 为嵌套对象，其他错误为错误信息字符串
+func marshalCause(err error, o renderOptions) ([]byte, error) {
+	switch c := err.(type) {
+	case *KError:
+		return c.marshalJSON(o)
+	case *privateError:
+		return marshalCause(c.err, o)
+	}
+	return json.Marshal(redactText(rawTextOf(err), o.redact))
+}
+
+// exposedError 按指定暴露级别输出的错误包装
+type exposedError struct {
+	err      error
+	exposure Exposure
+}
+
+// Expose 返回按指定暴露级别输出的错误包装，不受全局暴露级别影响，脱敏规则照常生效
+// 用于在生产环境向内部日志等输出目标写出完整信息：
+//
+//	klog.Error(ctx, fmt.Sprintf("%+v", kerr.Expose(err, kerr.Debug)))
+func Expose(err error, x Exposure) error {
+	if err == nil {
+		return nil
+	}
+	return &exposedError{err: err, exposure: x}
+}
+
+// Error 返回原错误的 Error()
+func (x *exposedError) Error() string {
+	return x.err.Error()
+}
+
+// Unwrap 返回原错误
+func (x *exposedError) Unwrap() error {
+	return x.err
+}
+
+// options 返回包装指定的输出选项
+func (x *exposedError) options() renderOptions {
+	return renderOptions{redact: redactionEnabled(), exposure: x.exposure}
+}
+
+// Format 按指定的暴露级别输出
+func (x *exposedError) Format(f fmt.State, c rune) {
+	if c == 'v' && f.Flag('+') {
+		fmt.Fprint(f, verboseOf(x.err, x.options()))
+		return
+	}
+	fmt.Fprint(f, textOf(x.err, x.options()))
+}
+
+// MarshalJSON 按指定的暴露级别序列化
+func (x *exposedError) MarshalJSON() ([]byte, error) {
+	return marshalOf(x.err, x.options())
+}
@@ -0,0 +1,189 @@
+package kerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// setExposure 设置暴露级别，测试结束后恢复
+func setExposure(t *testing.T, x Exposure) {
+	old := CurrentExposure()
+	SetExposure(x)
+	t.Cleanup(func() { SetExposure(old) })
+}
+
+// setPublicFields 设置 Public 级别允许输出的字段，测试结束后恢复
+func setPublicFields(t *testing.T, keys ...string) {
+	publicFields.mu.RLock()
+	old := publicFields.keys
+	publicFields.mu.RUnlock()
+	SetPublicFields(keys...)
+	t.Cleanup(func() {
+		publicFields.mu.Lock()
+		defer publicFields.mu.Unlock()
+		publicFields.keys = old
+	})
+}
+
+func TestExposedFields(t *testing.T) {
+	err := NoData.WithFields(map[string]any{"order_id": "A001", "sql": "select *", "password": "s3cr3t"})
+
+	setExposure(t, Public)
+	if got := ExposedFields(err); len(got) != 0 {
+		t.Errorf("public level must not expose fields by default, got %v", got)
+	}
+	setPublicFields(t, "order_id", "password")
+	if got := ExposedFields(err); len(got) != 2 || got["order_id"] != "A001" || got["password"] != RedactMask {
+		t.Errorf("public level must expose allowlisted fields only, redacted, got %v", got)
+	}
+	if p := ToProblem(err, ProblemOptions{}); p.Extensions["order_id"] != "A001" || p.Extensions["sql"] != nil {
+		t.Errorf("problem must follow ExposedFields, got %v", p.Extensions)
+	}
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, err)
+	if s := rec.Body.String(); !strings.Contains(s, `"fields":{`) || !strings.Contains(s, `"order_id":"A001"`) || strings.Contains(s, "select *") {
+		t.Errorf("WriteHTTP must follow ExposedFields, got %s", s)
+	}
+
+	SetExposure(Internal)
+	if got := ExposedFields(err); len(got) != 3 || got["sql"] != "select *" {
+		t.Errorf("internal level must expose all fields, got %v", got)
+	}
+}
+
+func TestParseExposure(t *testing.T) {
+	tests := map[string]Exposure{
+		"public":     Public,
+		" PROD ":     Public,
+		"production": Public,
+		"internal":   Internal,
+		"staging":    Internal,
+		"debug":      Debug,
+		"dev":        Debug,
+	}
+	for in, want := range tests {
+		if got, err := ParseExposure(in); err != nil || got != want {
+			t.Errorf("ParseExposure(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseExposure("verbose"); !errors.Is(err, ConfigError) {
+		t.Errorf("expected ConfigError for unknown exposure, got %v", err)
+	}
+	if Internal.String() != "internal" || Exposure(9).String() != "Exposure(9)" {
+		t.Errorf("unexpected String(): %s %s", Internal, Exposure(9))
+	}
+}
+
+func TestExposureFormat(t *testing.T) {
+	err := NoData.Wrap(errors.New("select failed")).WithField("table", "users").WithStack()
+
+	// Format 用于日志，不受暴露级别影响
+	for _, x := range []Exposure{Public, Internal, Debug} {
+		setExposure(t, x)
+		if got := fmt.Sprintf("%v", err); got != err.Error() {
+			t.Errorf("%v %%v = %q, want Error()", x, got)
+		}
+		if got := fmt.Errorf("load user: %w", err).Error(); got != "load user: no data: select failed" {
+			t.Errorf("%v %%w = %q", x, got)
+		}
+		verbose := fmt.Sprintf("%+v", err)
+		for _, want := range []string{"table=users", "Caused by: select failed", "expose_test.go"} {
+			if !strings.Contains(verbose, want) {
+				t.Errorf("%v %%+v must contain %q, got %s", x, want, verbose)
+			}
+		}
+	}
+}
+
+func TestExposureToJSON(t *testing.T) {
+	err := NoData.Wrap(errors.New("select failed")).WithField("table", "users").WithStack()
+	m := Join(NoData.Wrap(errors.New("a")), errors.New("b"))
+
+	// MarshalJSON 用于日志和服务间传递，不受暴露级别影响
+	setExposure(t, Public)
+	var doc map[string]any
+	if jsonErr := json.Unmarshal([]byte(err.ToJSON()), &doc); jsonErr != nil {
+		t.Fatalf("invalid JSON %s: %v", err.ToJSON(), jsonErr)
+	}
+	if doc["message"] != "no data" || doc["cause"] != "select failed" || doc["stack"] == nil || doc["fingerprint"] == nil || doc["fields"] == nil {
+		t.Errorf("ToJSON must keep the full content, got %v", doc)
+	}
+	if data, _ := json.Marshal(map[string]any{"err": err}); !strings.Contains(string(data), "select failed") {
+		t.Errorf("nested MarshalJSON must keep the full content, got %s", data)
+	}
+	if !strings.Contains(m.ToJSON(), `"errors"`) {
+		t.Errorf("MultiError ToJSON must keep its children, got %s", m.ToJSON())
+	}
+}
+
+func TestExposureDefault(t *testing.T) {
+	t.Setenv(ExposureEnv, "")
+	if got := exposureFromEnv(); got != Public {
+		t.Errorf("default exposure = %v, want public", got)
+	}
+	t.Setenv(ExposureEnv, "verbose")
+	if got := exposureFromEnv(); got != Public {
+		t.Errorf("unknown exposure must fall back to public, got %v", got)
+	}
+	t.Setenv(ExposureEnv, "staging")
+	if got := exposureFromEnv(); got != Internal {
+		t.Errorf("exposure from env = %v, want internal", got)
+	}
+}
+
+func TestExposureWriteHTTP(t *testing.T) {
+	err := NoData.Wrap(errors.New("select failed")).WithStack()
+
+	setExposure(t, Internal)
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, err)
+	var body struct {
+		Code    int            `json:"code"`
+		Message string         `json:"message"`
+		Error   map[string]any `json:"error"`
+	}
+	if jsonErr := json.Unmarshal(rec.Body.Bytes(), &body); jsonErr != nil {
+		t.Fatalf("Unmarshal failed: %v", jsonErr)
+	}
+	if body.Message != NoData.Display() || body.Error["cause"] != "select failed" || body.Error["stack"] != nil {
+		t.Errorf("internal body = %s", rec.Body.String())
+	}
+
+	SetExposure(Debug)
+	rec = httptest.NewRecorder()
+	WriteHTTP(rec, err)
+	if !strings.Contains(rec.Body.String(), `"stack"`) {
+		t.Errorf("debug body must include the stack, got %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	WriteHTTP(rec, nil)
+	if strings.Contains(rec.Body.String(), `"error"`) {
+		t.Errorf("success body must not include error, got %s", rec.Body.String())
+	}
+}
+
+func TestExpose(t *testing.T) {
+	setExposure(t, Public)
+	err := NoData.Wrap(errors.New("select failed")).WithStack()
+
+	if got := fmt.Sprintf("%+v", Expose(err, Debug)); !strings.Contains(got, "select failed") || !strings.Contains(got, "expose_test.go") {
+		t.Errorf("Expose(Debug) %%+v = %s", got)
+	}
+	if data, _ := json.Marshal(Expose(err, Internal)); !strings.Contains(string(data), "select failed") || strings.Contains(string(data), "stack") {
+		t.Errorf("Expose(Internal) JSON = %s", data)
+	}
+	if got := fmt.Sprintf("%v", Expose(errors.New("dial tcp: refused"), Public)); got != SystemError.Display() {
+		t.Errorf("public foreign errors must render as SystemError, got %q", got)
+	}
+	if !errors.Is(Expose(err, Debug), NoData) || Expose(nil, Debug) != nil {
+		t.Errorf("Expose must keep the error chain")
+	}
+	if got := fmt.Sprintf("%+v", Trusted(err)); !strings.Contains(got, "expose_test.go") {
+		t.Errorf("Trusted must ignore the exposure, got %s", got)
+	}
+}
@@ -0,0 +1,195 @@
+package kerr
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"path"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FingerprintOptions 错误指纹的计算选项
+type FingerprintOptions struct {
+	// Frames 参与计算的堆栈帧数量，默认为 5
+	Frames int
+	// IgnoreLines 为 true 时堆栈帧只取函数名，忽略行号，避免无关改动导致指纹变化
+	IgnoreLines bool
+	// Packages 参与计算的包路径前缀，如 "github.com/kearth/"；为空时取所有非标准库的帧
+	// kerr 自身（含子包）及模块缓存中第三方依赖的帧始终不参与计算
+	Packages []string
+}
+
+// fingerprinting 错误指纹的计算选项，gen 在选项变化时递增，使调用栈上缓存的指纹失效
+var fingerprinting = struct {
+	mu   sync.RWMutex
+	opts FingerprintOptions
+	gen  uint64
+}{
+	opts: FingerprintOptions{Frames: 5},
+}
+
+// SetFingerprintOptions 设置错误指纹的计算选项，Frames 小于等于 0 时使用默认值
+func SetFingerprintOptions(opts FingerprintOptions) {
+	if opts.Frames <= 0 {
+		opts.Frames = 5
+	}
+	opts.Packages = append([]string(nil), opts.Packages...)
+	fingerprinting.mu.Lock()
+	defer fingerprinting.mu.Unlock()
+	fingerprinting.opts = opts
+	fingerprinting.gen++
+}
+
+// kerrDir kerr 源码所在目录，用于识别 kerr 自身的堆栈帧
+var kerrDir = func() string {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return ""
+	}
+	return path.Dir(file) + "/"
+}()
+
+// Fingerprint 返回错误指纹，用于聚合同类错误
+// 由错误码、错误链上各错误的类型以及第一个堆栈中属于应用自身的前 N 帧计算，
+// 不包含错误信息，因此信息中的 ID 等变化不影响指纹；从 JSON 还原的错误沿用远端的指纹
+// 带有调用栈的错误首次计算后指纹缓存在调用栈上，派生的错误实例共享缓存
+func (e *KError) Fingerprint() string {
+	if e.stack == nil && e.fingerprint != "" {
+		return e.fingerprint
+	}
+	return fingerprintOf(e, e.code)
+}
+
+// Fingerprint 返回聚合错误的指纹，计算方式同 KError.Fingerprint，子错误的类型和堆栈均参与计算
+func (m *MultiError) Fingerprint() string {
+	return fingerprintOf(m, m.Code())
+}
+
+// Fingerprint 返回任意错误的指纹，nil 返回空字符串
+// 实现了 Fingerprint() string 的错误使用自身的指纹，其他错误按 CodeOf 的错误码和错误链类型计算
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+	if f, ok := err.(interface{ Fingerprint() string }); ok {
+		return f.Fingerprint()
+	}
+	return fingerprintOf(err, CodeOf(err))
+}
+
+// fingerprintOf 计算错误码、错误链类型和堆栈帧的哈希，有调用栈时按错误码和错误链类型缓存在调用栈上
+func fingerprintOf(err error, code int) string {
+	fingerprinting.mu.RLock()
+	opts, gen := fingerprinting.opts, fingerprinting.gen
+	fingerprinting.mu.RUnlock()
+
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(code))
+	var st *stack
+	for _, e := range Chain(err) {
+		b.WriteByte('\n')
+		b.WriteString(reflect.TypeOf(e).String())
+		if s, ok := e.(interface{ callStack() *stack }); ok && st == nil {
+			st = s.callStack()
+		}
+	}
+	key := b.String()
+	if st == nil {
+		return hashFingerprint(key, nil)
+	}
+	return st.fingerprint(key, gen, func() string {
+		return hashFingerprint(key, fingerprintFrames(st, opts))
+	})
+}
+
+// hashFingerprint 计算错误码、错误链类型和堆栈帧的哈希
+func hashFingerprint(key string, frames []string) string {
+	h := sha1.New()
+	h.Write([]byte(key))
+	for _, frame := range frames {
+		h.Write([]byte{'\n'})
+		h.Write([]byte(frame))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// fingerprint 返回缓存在调用栈上的指纹，key 为错误码和错误链类型
+// 未缓存或计算选项已变化（gen 不同）时调用 compute 计算并缓存
+func (s *stack) fingerprint(key string, gen uint64, compute func() string) string {
+	s.fpMu.Lock()
+	if s.fpGen == gen {
+		if fp, ok := s.fingerprints[key]; ok {
+			s.fpMu.Unlock()
+			return fp
+		}
+	}
+	s.fpMu.Unlock()
+
+	fp := compute()
+	s.fpMu.Lock()
+	defer s.fpMu.Unlock()
+	if s.fpGen != gen || s.fingerprints == nil {
+		s.fpGen, s.fingerprints = gen, make(map[string]string, 1)
+	}
+	s.fingerprints[key] = fp
+	return fp
+}
+
+// fingerprintFrames 返回参与计算的堆栈帧，跳过 runtime、标准库、kerr 自身及模块缓存中依赖的帧
+func fingerprintFrames(st *stack, opts FingerprintOptions) []string {
+	if st == nil {
+		return nil
+	}
+	var out []string
+	for _, frame := range st.Frames() {
+		if len(out) >= opts.Frames {
+			break
+		}
+		if frame.Function == "" || isLibraryFile(frame.File) || !isOwnFunction(frame.Function, opts.Packages) {
+			continue
+		}
+		if opts.IgnoreLines {
+			out = append(out, frame.Function)
+		} else {
+			out = append(out, frame.Function+":"+strconv.Itoa(frame.Line))
+		}
+	}
+	return out
+}
+
+// isOwnFunction 判断函数是否属于应用自身的包
+// 未指定包路径前缀时，单段路径或首段不含 "." 的包（标准库、runtime）不属于应用，main 包除外
+func isOwnFunction(function string, packages []string) bool {
+	if len(packages) > 0 {
+		for _, p := range packages {
+			if strings.HasPrefix(function, p) {
+				return true
+			}
+		}
+		return false
+	}
+	if strings.HasPrefix(function, "main.") {
+		return true
+	}
+	first, _, ok := strings.Cut(function, "/")
+	return ok && strings.Contains(first, ".")
+}
+
+// isLibraryFile 判断堆栈帧的源文件是否属于 kerr 自身（含子包，测试文件除外）或模块缓存中的依赖
+// 模块缓存中的文件所在目录含 "module@version" 形式的路径段，如 ".../pkg/mod/google.golang.org/grpc@v1.60.0/server.go"，
+// -trimpath 编译时为 "google.golang.org/grpc@v1.60.0/server.go"
+func isLibraryFile(file string) bool {
+	if kerrDir != "" && strings.HasPrefix(file, kerrDir) && !strings.HasSuffix(file, "_test.go") {
+		return true
+	}
+	dir, _ := path.Split(file)
+	for _, segment := range strings.Split(dir, "/") {
+		if _, version, ok := strings.Cut(segment, "@"); ok && strings.HasPrefix(version, "v") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package kerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// stackAtA、stackAtB 在不同的函数中收集堆栈，用于比较指纹
+func stackAtA() Error { return SystemError.Wrap(io.EOF).WithStack() }
+func stackAtB() Error { return SystemError.Wrap(io.EOF).WithStack() }
+
+// twoLines 在同一函数的两行分别收集堆栈
+func twoLines() (Error, Error) {
+	a := SystemError.Wrap(io.EOF).WithStack()
+	b := SystemError.Wrap(io.EOF).WithStack()
+	return a, b
+}
+
+func resetFingerprintOptions(t *testing.T) {
+	t.Cleanup(func() { SetFingerprintOptions(FingerprintOptions{}) })
+}
+
+func TestFingerprintStable(t *testing.T) {
+	a := NoData.Wrap(errors.New("order 1001 not found")).WithMessage("load %d", 1001)
+	b := NoData.Wrap(errors.New("order 2002 not found")).WithMessage("load %d", 2002)
+	if a.(*KError).Fingerprint() != b.(*KError).Fingerprint() {
+		t.Errorf("messages must not affect the fingerprint")
+	}
+	if Fingerprint(a) == Fingerprint(ValidationFailed.Wrap(errors.New("x"))) {
+		t.Errorf("different codes must have different fingerprints")
+	}
+	if Fingerprint(a) == Fingerprint(NoData.Wrap(&os.PathError{Op: "open", Path: "x", Err: io.EOF})) {
+		t.Errorf("different cause types must have different fingerprints")
+	}
+	if len(Fingerprint(a)) != 16 {
+		t.Errorf("unexpected fingerprint %q", Fingerprint(a))
+	}
+
+	var errs []string
+	for i := 0; i < 2; i++ {
+		errs = append(errs, Fingerprint(stackAtA()))
+	}
+	if errs[0] != errs[1] {
+		t.Errorf("errors from the same call site must share a fingerprint")
+	}
+	if Fingerprint(stackAtA()) == Fingerprint(stackAtB()) {
+		t.Errorf("errors from different call sites must have different fingerprints")
+	}
+}
+
+func TestFingerprintOptions(t *testing.T) {
+	resetFingerprintOptions(t)
+
+	a, b := twoLines()
+	if Fingerprint(a) == Fingerprint(b) {
+		t.Errorf("line numbers are part of the fingerprint by default")
+	}
+	SetFingerprintOptions(FingerprintOptions{IgnoreLines: true})
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Errorf("expected line numbers to be ignored")
+	}
+
+	SetFingerprintOptions(FingerprintOptions{Packages: []string{"example.com/"}})
+	if Fingerprint(stackAtA()) != Fingerprint(stackAtB()) {
+		t.Errorf("frames outside the configured packages must be ignored")
+	}
+	SetFingerprintOptions(FingerprintOptions{Frames: 1})
+	if Fingerprint(stackAtA()) == Fingerprint(stackAtB()) {
+		t.Errorf("expected the top frame to be used")
+	}
+}
+
+func TestIsOwnFunction(t *testing.T) {
+	cases := []struct {
+		function string
+		packages []string
+		want     bool
+	}{
+		{"runtime.goexit", nil, false},
+		{"testing.tRunner", nil, false},
+		{"net/http.(*conn).serve", nil, false},
+		{"internal/runtime/maps.fatal", nil, false},
+		{"main.main", nil, true},
+		{"github.com/kearth/klib/kerr.New", nil, true},
+		{"github.com/kearth/klib/kerr.New", []string{"github.com/acme/"}, false},
+		{"github.com/acme/order.(*Service).Get", []string{"github.com/acme/"}, true},
+	}
+	for _, c := range cases {
+		if got := isOwnFunction(c.function, c.packages); got != c.want {
+			t.Errorf("isOwnFunction(%q, %v) = %v, want %v", c.function, c.packages, got, c.want)
+		}
+	}
+}
+
+func TestIsLibraryFile(t *testing.T) {
+	cases := []struct {
+		file string
+		want bool
+	}{
+		{kerrDir + "errors.go", true},
+		{kerrDir + "kgrpc/interceptor.go", true},
+		{kerrDir + "fingerprint_test.go", false},
+		{"/root/go/pkg/mod/google.golang.org/grpc@v1.60.0/server.go", true},
+		{"google.golang.org/grpc@v1.60.0/server.go", true},
+		{"/home/dev/acme@corp/order/service.go", false},
+		{"/home/dev/order/service.go", false},
+	}
+	for _, c := range cases {
+		if got := isLibraryFile(c.file); got != c.want {
+			t.Errorf("isLibraryFile(%q) = %v, want %v", c.file, got, c.want)
+		}
+	}
+}
+
+func TestFingerprintCache(t *testing.T) {
+	resetFingerprintOptions(t)
+
+	err := stackAtA()
+	first := Fingerprint(err)
+	st := err.(*KError).stack
+	if len(st.fingerprints) != 1 {
+		t.Fatalf("expected the fingerprint to be cached on the stack, got %v", st.fingerprints)
+	}
+	if Fingerprint(err.WithField("k", "v")) != first || len(st.fingerprints) != 1 {
+		t.Errorf("derived errors must share the cached fingerprint")
+	}
+	if Fingerprint(err.Wrap(&os.PathError{Op: "open", Path: "x", Err: io.EOF})) == first {
+		t.Errorf("a different error chain must not reuse the cached fingerprint")
+	}
+
+	SetFingerprintOptions(FingerprintOptions{Frames: 1, IgnoreLines: true})
+	if Fingerprint(err) == first {
+		t.Errorf("changing the options must invalidate the cache")
+	}
+}
+
+func TestFingerprintForeignAndJSON(t *testing.T) {
+	if Fingerprint(nil) != "" {
+		t.Errorf("expected empty fingerprint for nil")
+	}
+	if Fingerprint(errors.New("a")) != Fingerprint(errors.New("b")) {
+		t.Errorf("foreign errors of the same type must share a fingerprint")
+	}
+	if Fingerprint(fmt.Errorf("x: %w", NoData)) == Fingerprint(NoData) {
+		t.Errorf("wrapper types are part of the fingerprint")
+	}
+	multi := Join(NoData, io.EOF)
+	if Fingerprint(multi) != multi.(*MultiError).Fingerprint() || Fingerprint(multi) == Fingerprint(NoData) {
+		t.Errorf("unexpected MultiError fingerprint")
+	}
+
+	err := stackAtA()
+	var doc map[string]any
+	if jsonErr := json.Unmarshal([]byte(err.ToJSON()), &doc); jsonErr != nil {
+		t.Fatalf("Unmarshal failed: %v", jsonErr)
+	}
+	if doc["fingerprint"] != Fingerprint(err) {
+		t.Errorf("expected fingerprint in JSON, got %v", doc["fingerprint"])
+	}
+	restored, jsonErr := FromJSON([]byte(err.ToJSON()))
+	if jsonErr != nil {
+		t.Fatalf("FromJSON failed: %v", jsonErr)
+	}
+	if restored.Fingerprint() != Fingerprint(err) {
+		t.Errorf("expected remote fingerprint to be kept, got %s", restored.Fingerprint())
+	}
+	if multiJSON := multi.ToJSON(); !strings.Contains(multiJSON, `"fingerprint":"`+Fingerprint(multi)+`"`) {
+		t.Errorf("expected fingerprint in MultiError JSON, got %s", multiJSON)
+	}
+}
@@ -0,0 +1,200 @@
+package kerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// httpStatusRange 错误码区间到 HTTP 状态码的映射，区间为闭区间 [min, max]
+type httpStatusRange struct {
+	min    int
+	max    int
+	status int
+}
+
+// httpMapping HTTP 状态码映射表，精确错误码优先于区间
+var httpMapping = struct {
+	mu     sync.RWMutex
+	codes  map[int]int
+	ranges []httpStatusRange
+}{
+	codes: map[int]int{
+		0: http.StatusOK,
+
+		10002: http.StatusGatewayTimeout, // TimeoutError
+
+		20000: http.StatusUnauthorized,    // Unauthorized
+		20001: http.StatusForbidden,       // Forbidden
+		20002: http.StatusNotFound,        // UserNotFound
+		20003: http.StatusUnauthorized,    // PasswordError
+		20004: http.StatusUnauthorized,    // TokenExpired
+		20005: http.StatusForbidden,       // AccountLocked
+		20006: http.StatusTooManyRequests, // QuotaExceeded
+
+		30001: http.StatusConflict,   // OperationConflict
+		30003: http.StatusNotFound,   // NoData
+		30004: http.StatusBadRequest, // ValidationFailed
+
+		40000: http.StatusServiceUnavailable, // DBError
+		40001: http.StatusServiceUnavailable, // CacheError
+		40004: http.StatusServiceUnavailable, // MessageQueueError
+
+		50002: http.StatusServiceUnavailable, // ResourcePoolEmpty
+		50003: http.StatusServiceUnavailable, // ServiceNotReady
+	},
+	ranges: []httpStatusRange{
+		{min: 10000, max: 19999, status: http.StatusInternalServerError},
+		{min: 20000, max: 29999, status: http.StatusUnauthorized},
+		{min: 30000, max: 39999, status: http.StatusBadRequest},
+		{min: 40000, max: 49999, status: http.StatusBadGateway},
+		{min: 50000, max: 59999, status: http.StatusInternalServerError},
+	},
+}
+
+// SetHTTPStatus 设置指定错误码对应的 HTTP 状态码
+func SetHTTPStatus(code int, status int) {
+	httpMapping.mu.Lock()
+	defer httpMapping.mu.Unlock()
+	httpMapping.codes[code] = status
+}
+
+// SetHTTPStatusRange 设置错误码区间 [min, max] 对应的 HTTP 状态码
+// 后设置的区间优先匹配，可用于覆盖默认区间
+func SetHTTPStatusRange(min, max int, status int) {
+	httpMapping.mu.Lock()
+	defer httpMapping.mu.Unlock()
+	httpMapping.ranges = append([]httpStatusRange{{min: min, max: max, status: status}}, httpMapping.ranges...)
+}
+
+// HTTPStatus 返回错误对应的 HTTP 状态码
+// nil 返回 200，非 kerr 错误及未匹配的错误码返回 500
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var e Error
+	if !errors.As(err, &e) {
+		return http.StatusInternalServerError
+	}
+	return httpStatusOf(e.Code())
+}
+
+// httpStatusOf 按错误码查找 HTTP 状态码
+func httpStatusOf(code int) int {
+	httpMapping.mu.RLock()
+	defer httpMapping.mu.RUnlock()
+
+	if status, ok := httpMapping.codes[code]; ok {
+		return status
+	}
+	for _, r := range httpMapping.ranges {
+		if code >= r.min && code <= r.max {
+			return r.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// statusCodes HTTP 状态码到错误码的反向映射，用于还原无法解析的 HTTP 错误响应
+var statusCodes = map[int]int{
+	http.StatusOK:                  0,
+	http.StatusBadRequest:          30004, // ValidationFailed
+	http.StatusUnauthorized:        20000, // Unauthorized
+	http.StatusForbidden:           20001, // Forbidden
+	http.StatusNotFound:            30003, // NoData
+	http.StatusConflict:            30001, // OperationConflict
+	http.StatusTooManyRequests:     20006, // QuotaExceeded
+	http.StatusBadGateway:          40003, // ThirdPartyError
+	http.StatusServiceUnavailable:  50003, // ServiceNotReady
+	http.StatusGatewayTimeout:      10002, // TimeoutError
+	http.StatusInternalServerError: 10000, // SystemError
+}
+
+// codeOfHTTPStatus 按 HTTP 状态码反查错误码
+func codeOfHTTPStatus(status int) int {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return SystemError.Code()
+	}
+	return ThirdPartyError.Code()
+}
+
+// httpBody HTTP 错误响应体，与 ToJSON 字段保持一致，message 为 Display 信息
+type httpBody struct {
+	Code    int             `json:"code"`
+	Msg     string          `json:"message"`
+	Display string          `json:"display,omitempty"`
+	Details detailList      `json:"details,omitempty"` // 错误链上的错误详情，任何暴露级别均输出
+	Fields  map[string]any  `json:"fields,omitempty"`  // 按暴露级别允许输出的结构化字段
+	Error   json.RawMessage `json:"error,omitempty"`   // 暴露级别为 Internal、Debug 时的内部错误信息
+}
+
+// WriteHTTP 将错误写入 HTTP 响应
+// 状态码按 HTTPStatus 映射，响应体包含错误码、Display 信息、错误链上的错误详情（如 FieldViolation）
+// 及 ExposedFields 允许的结构化字段；
+// 暴露级别为 Public 时不暴露内部错误信息和堆栈，
+// Internal、Debug 时在 error 成员中附加对应级别的 ToJSON 输出；
+// nil 视为 Succ，非 kerr 错误按 SystemError 处理；只负责渲染，不上报错误，
+// 上报由返回错误的边界（如 kunit.Call、kgrpc 拦截器）或调用方通过 Report 完成
+func WriteHTTP(w http.ResponseWriter, err error) {
+	var e Error
+	switch {
+	case err == nil:
+		e = Succ
+	case !errors.As(err, &e):
+		e = SystemError.Wrap(err)
+	}
+	resp := httpBody{
+		Code:    e.Code(),
+		Msg:     e.Display(),
+		Display: e.Display(),
+		Details: allDetails(e),
+		Fields:  ExposedFields(e),
+	}
+	if o := defaultRender(); err != nil && o.exposure != Public {
+		resp.Error, _ = marshalOf(e, o)
+	}
+	body, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(httpStatusOf(e.Code()))
+	_, _ = w.Write(body)
+}
+
+// FromHTTPResponse 将 HTTP 错误响应还原为 kerr 错误，会读取并关闭响应体
+// resp 为 nil 时返回 nil；响应体中的错误详情还原为 Details
+// 响应体的错误码为 0 时仅在 2xx 响应中采用，以免网关等返回的 {"message": "..."} 被当作成功；
+// 响应体无法解析或错误码不可信时，按 HTTP 状态码反查错误码，保留响应体中的错误信息，错误码已注册时沿用注册的 Display
+func FromHTTPResponse(resp *http.Response) Error {
+	if resp == nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	var body *KError
+	if data, err := io.ReadAll(resp.Body); err == nil {
+		if e, err := FromJSON(data); err == nil && (e.code != 0 || e.msg != "") {
+			body = e
+		}
+	}
+	if body != nil && (body.code != 0 || resp.StatusCode/100 == 2) {
+		return body
+	}
+
+	code := codeOfHTTPStatus(resp.StatusCode)
+	e := New(code, fmt.Sprintf("http status %d", resp.StatusCode))
+	if body != nil {
+		e.msg = body.msg
+		e.fields = body.fields
+		e.details = body.details
+	}
+	if registered, ok := Lookup(code); ok {
+		e.display = registered.Display()
+	}
+	return e
+}
@@ -0,0 +1,125 @@
+package kerr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{nil, http.StatusOK},
+		{Unauthorized, http.StatusUnauthorized},
+		{TokenExpired, http.StatusUnauthorized},
+		{Forbidden, http.StatusForbidden},
+		{QuotaExceeded, http.StatusTooManyRequests},
+		{NoData, http.StatusNotFound},
+		{ValidationFailed, http.StatusBadRequest},
+		{InvalidState, http.StatusBadRequest},
+		{NetworkError, http.StatusBadGateway},
+		{DBError, http.StatusServiceUnavailable},
+		{TimeoutError, http.StatusGatewayTimeout},
+		{SystemError, http.StatusInternalServerError},
+		{NoData.Wrap(errors.New("cause")).WithStack(), http.StatusNotFound},
+		{errors.New("plain"), http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		if got := HTTPStatus(c.err); got != c.status {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", c.err, got, c.status)
+		}
+	}
+}
+
+func TestSetHTTPStatus(t *testing.T) {
+	t.Cleanup(snapshotHTTPMapping())
+	SetHTTPStatus(96100, http.StatusTeapot)
+	if got := HTTPStatus(New(96100, "teapot")); got != http.StatusTeapot {
+		t.Errorf("expected custom code mapping, got %d", got)
+	}
+
+	SetHTTPStatusRange(96200, 96299, http.StatusPaymentRequired)
+	if got := HTTPStatus(New(96250, "payment")); got != http.StatusPaymentRequired {
+		t.Errorf("expected custom range mapping, got %d", got)
+	}
+}
+
+func TestWriteHTTP(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, NoData.Wrap(errors.New("select * from users: secret")).WithStack())
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("expected json content type, got %q", ct)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "secret") || strings.Contains(body, "no data") {
+		t.Errorf("response body must only expose display, got %s", body)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if result["code"] != float64(30003) || result["display"] != NoData.Display() {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	WriteHTTP(rec, errors.New("plain"))
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "plain") {
+		t.Errorf("foreign errors should be written as SystemError, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFromHTTPResponse(t *testing.T) {
+	if err := FromHTTPResponse(nil); err != nil {
+		t.Errorf("expected untyped nil for a nil response, got %#v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, ValidationFailed)
+
+	err := FromHTTPResponse(rec.Result())
+	if !errors.Is(err, ValidationFailed) {
+		t.Errorf("expected decoded error to be ValidationFailed, got %v", err)
+	}
+	if err.Display() != ValidationFailed.Display() {
+		t.Errorf("expected display %q, got %q", ValidationFailed.Display(), err.Display())
+	}
+
+	rec = httptest.NewRecorder()
+	rec.WriteHeader(http.StatusNotFound)
+	_, _ = rec.WriteString("404 page not found")
+
+	err = FromHTTPResponse(rec.Result())
+	if HTTPStatus(err) != http.StatusNotFound {
+		t.Errorf("expected non-json body to fall back to status mapping, got %v", err)
+	}
+	if !errors.Is(err, NoData) || err.Display() != NoData.Display() {
+		t.Errorf("expected 404 to be decoded as NoData, got %v", err)
+	}
+
+	// 网关等返回的没有错误码的响应体不能当作成功
+	rec = httptest.NewRecorder()
+	rec.WriteHeader(http.StatusGatewayTimeout)
+	_, _ = rec.WriteString(`{"message":"upstream request timeout"}`)
+
+	err = FromHTTPResponse(rec.Result())
+	if err.Code() != TimeoutError.Code() || err.Error() != "upstream request timeout" || err.Display() != TimeoutError.Display() {
+		t.Errorf("expected code 0 on a 504 to fall back to the status mapping, got %d %q %q", err.Code(), err.Error(), err.Display())
+	}
+
+	rec = httptest.NewRecorder()
+	_, _ = rec.WriteString(`{"code":0,"message":"success"}`)
+	if err = FromHTTPResponse(rec.Result()); err.Code() != 0 || err.Error() != "success" {
+		t.Errorf("expected code 0 to be accepted on a 2xx response, got %d %q", err.Code(), err.Error())
+	}
+}
@@ -0,0 +1,218 @@
+package kerr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/kearth/klib/kctx"
+)
+
+// LocaleKey kctx 元数据中保存语言的键，如 ctx.Set(kerr.LocaleKey, "zh-TW")
+const LocaleKey = "Locale"
+
+// catalogs 多语言显示信息目录，语言（小写）-> 错误码 -> 显示信息模板
+var catalogs = struct {
+	mu            sync.RWMutex
+	messages      map[string]map[int]string
+	fallbacks     map[string][]string
+	defaultLocale string
+	templates     sync.Map // 显示信息 -> *template.Template
+}{
+	messages:  make(map[string]map[int]string),
+	fallbacks: make(map[string][]string),
+}
+
+// normalizeLocale 统一语言格式，如 zh_TW -> zh-tw
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// AddMessages 添加指定语言的显示信息，同一错误码后添加的覆盖先添加的
+// 显示信息支持 text/template 模板参数，数据来自错误的结构化字段，
+// 以及 Newf 的格式化参数 .Args，如 "订单 {{.order_id}} 不存在"
+func AddMessages(locale string, messages map[int]string) {
+	locale = normalizeLocale(locale)
+	catalogs.mu.Lock()
+	defer catalogs.mu.Unlock()
+
+	catalog, ok := catalogs.messages[locale]
+	if !ok {
+		catalog = make(map[int]string, len(messages))
+		catalogs.messages[locale] = catalog
+	}
+	for code, msg := range messages {
+		catalog[code] = msg
+	}
+}
+
+// LoadCatalog 从 JSON 加载指定语言的显示信息，JSON 的键为错误码，如 {"30003": "No data"}
+func LoadCatalog(locale string, data []byte) error {
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("load catalog %s: %w", locale, err)
+	}
+	messages := make(map[int]string, len(raw))
+	for key, msg := range raw {
+		code, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("load catalog %s: invalid code %q", locale, key)
+		}
+		messages[code] = msg
+	}
+	AddMessages(locale, messages)
+	return nil
+}
+
+// LoadCatalogFS 从文件系统加载匹配 pattern 的 JSON 目录，文件名（不含扩展名）作为语言
+// 可配合 embed.FS 使用：
+//
+//	//go:embed i18n/*.json
+//	var i18nFS embed.FS
+//	kerr.LoadCatalogFS(i18nFS, "i18n/*.json")
+func LoadCatalogFS(fsys fs.FS, pattern string) error {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		locale := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		if err := LoadCatalog(locale, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadCatalogDir 从目录加载所有 JSON 目录，如 i18n/en.json、i18n/zh-TW.json
+func LoadCatalogDir(dir string) error {
+	return LoadCatalogFS(os.DirFS(dir), "*.json")
+}
+
+// SetDefaultLocale 设置默认语言，作为所有回退链的最后一环
+func SetDefaultLocale(locale string) {
+	catalogs.mu.Lock()
+	defer catalogs.mu.Unlock()
+	catalogs.defaultLocale = normalizeLocale(locale)
+}
+
+// SetFallback 设置语言的回退链，未设置时按语言子标签逐级回退，如 zh-Hant-TW -> zh-Hant -> zh
+func SetFallback(locale string, chain ...string) {
+	normalized := make([]string, 0, len(chain))
+	for _, l := range chain {
+		normalized = append(normalized, normalizeLocale(l))
+	}
+	catalogs.mu.Lock()
+	defer catalogs.mu.Unlock()
+	catalogs.fallbacks[normalizeLocale(locale)] = normalized
+}
+
+// localeChain 返回语言的完整查找链：自身 -> 回退链 -> 默认语言
+func localeChain(locale string) []string {
+	locale = normalizeLocale(locale)
+	catalogs.mu.RLock()
+	defer catalogs.mu.RUnlock()
+
+	var chain []string
+	if locale != "" {
+		chain = append(chain, locale)
+		if fallback, ok := catalogs.fallbacks[locale]; ok {
+			chain = append(chain, fallback...)
+		} else {
+			for i := strings.LastIndex(locale, "-"); i > 0; i = strings.LastIndex(locale, "-") {
+				locale = locale[:i]
+				chain = append(chain, locale)
+			}
+		}
+	}
+	if catalogs.defaultLocale != "" {
+		chain = append(chain, catalogs.defaultLocale)
+	}
+	return chain
+}
+
+// lookupMessage 按查找链获取错误码的显示信息模板
+func lookupMessage(locale string, code int) (string, bool) {
+	chain := localeChain(locale)
+	catalogs.mu.RLock()
+	defer catalogs.mu.RUnlock()
+
+	for _, l := range chain {
+		if msg, ok := catalogs.messages[l][code]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// renderMessage 使用错误的字段和参数渲染显示信息模板，渲染失败时原样返回
+func renderMessage(msg string, fields map[string]any, args []any) string {
+	if !strings.Contains(msg, "{{") {
+		return msg
+	}
+	var tmpl *template.Template
+	if cached, ok := catalogs.templates.Load(msg); ok {
+		tmpl = cached.(*template.Template)
+	} else {
+		parsed, err := template.New("display").Option("missingkey=zero").Parse(msg)
+		if err != nil {
+			return msg
+		}
+		catalogs.templates.Store(msg, parsed)
+		tmpl = parsed
+	}
+
+	data := make(map[string]any, len(fields)+1)
+	for k, v := range fields {
+		data[k] = v
+	}
+	data["Args"] = args
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return msg
+	}
+	return b.String()
+}
+
+// DisplayFor 返回适用于 ctx 语言的显示信息
+// 语言取自 kctx 元数据中的 LocaleKey；nil 返回 Succ 的显示信息，
+// 错误链上的第一个 kerr 错误决定显示信息，没有 kerr 错误时按 SystemError 处理
+func DisplayFor(ctx context.Context, err error) string {
+	locale := LocaleOf(ctx)
+	if err == nil {
+		return Succ.DisplayIn(locale)
+	}
+	var e Error
+	if !errors.As(err, &e) {
+		e = SystemError.Wrap(err)
+	}
+	return e.DisplayIn(locale)
+}
+
+// LocaleOf 返回 ctx 中 kctx 元数据记录的语言，未设置时返回空字符串
+func LocaleOf(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if kc, ok := ctx.(kctx.Context); ok {
+		return kc.Get(LocaleKey)
+	}
+	if meta, ok := ctx.Value(kctx.MetaMapKey).(map[string]string); ok {
+		return meta[LocaleKey]
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package kerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kearth/klib/kctx"
+)
+
+func TestDisplayIn(t *testing.T) {
+	t.Cleanup(snapshotCatalogs())
+	if err := LoadCatalogDir("testdata/i18n"); err != nil {
+		t.Fatalf("LoadCatalogDir failed: %v", err)
+	}
+	AddMessages("zh", map[int]string{30004: "数据验证失败：{{.field}}"})
+
+	cases := []struct {
+		err     Error
+		locale  string
+		display string
+	}{
+		{NoData, "en", "No data found"},
+		{NoData, "en-US", "No data found"},
+		{NoData, "zh_TW", "沒有符合條件的資料"},
+		{NoData, "fr", NoData.Display()},
+		{NoData, "", NoData.Display()},
+		{ValidationFailed.WithField("field", "email"), "en", "Validation failed: email"},
+		{ValidationFailed.WithField("field", "email"), "zh-CN", "数据验证失败：email"},
+		{Newf(96600, "order %s not found", "A001"), "en", "Order A001 not found"},
+		{Join(NoData, TimeoutError), "en", "No data found"},
+	}
+	for _, c := range cases {
+		if got := c.err.DisplayIn(c.locale); got != c.display {
+			t.Errorf("DisplayIn(%q) of %v = %q, want %q", c.locale, c.err, got, c.display)
+		}
+	}
+}
+
+func TestLocaleFallback(t *testing.T) {
+	t.Cleanup(snapshotCatalogs())
+	AddMessages("ja", map[int]string{96601: "注文が見つかりません"})
+	AddMessages("en", map[int]string{96601: "Order not found"})
+	err := New(96601, "order not found").WithDisplay("订单不存在")
+
+	SetFallback("ja-JP", "ja", "en")
+	if got := err.DisplayIn("ja-JP"); got != "注文が見つかりません" {
+		t.Errorf("expected explicit fallback chain, got %q", got)
+	}
+
+	SetFallback("ko", "en")
+	if got := err.DisplayIn("ko"); got != "Order not found" {
+		t.Errorf("expected ko to fall back to en, got %q", got)
+	}
+
+	SetDefaultLocale("en")
+	if got := err.DisplayIn("de"); got != "Order not found" {
+		t.Errorf("expected default locale to be the last fallback, got %q", got)
+	}
+}
+
+func TestLoadCatalogFS(t *testing.T) {
+	t.Cleanup(snapshotCatalogs())
+	fsys := fstest.MapFS{
+		"i18n/ja.json":  {Data: []byte(`{"96602": "在庫がありません"}`)},
+		"i18n/bad.json": {Data: []byte(`{"code": "x"}`)},
+	}
+	if err := LoadCatalogFS(fsys, "i18n/ja.json"); err != nil {
+		t.Fatalf("LoadCatalogFS failed: %v", err)
+	}
+	if got := New(96602, "out of stock").DisplayIn("ja"); got != "在庫がありません" {
+		t.Errorf("unexpected display %q", got)
+	}
+	if err := LoadCatalogFS(fsys, "i18n/*.json"); err == nil {
+		t.Errorf("expected error for invalid code key")
+	}
+}
+
+func TestDisplayFor(t *testing.T) {
+	t.Cleanup(snapshotCatalogs())
+	AddMessages("en", map[int]string{
+		0:     "Success",
+		10000: "System error, please try again later",
+		96603: "Coupon expired",
+	})
+	coupon := New(96603, "coupon expired").WithDisplay("优惠券已过期")
+
+	ctx := kctx.New()
+	ctx.Set(LocaleKey, "en-GB")
+	if got := DisplayFor(ctx, coupon); got != "Coupon expired" {
+		t.Errorf("expected locale from kctx metadata, got %q", got)
+	}
+	if got := DisplayFor(ctx, errors.New("plain")); got != "System error, please try again later" {
+		t.Errorf("expected foreign errors to use SystemError, got %q", got)
+	}
+	if got := DisplayFor(ctx, nil); got != "Success" {
+		t.Errorf("expected nil to use Succ, got %q", got)
+	}
+
+	plain := context.WithValue(context.Background(), kctx.MetaMapKey, map[string]string{LocaleKey: "en"})
+	if got := DisplayFor(plain, coupon); got != "Coupon expired" {
+		t.Errorf("expected locale from metadata of a standard context, got %q", got)
+	}
+	if got := DisplayFor(context.Background(), coupon); got != "优惠券已过期" {
+		t.Errorf("expected default display without locale, got %q", got)
+	}
+	if got := DisplayFor(ctx, fmt.Errorf("redeem: %w", coupon)); got != "Coupon expired" {
+		t.Errorf("expected wrapped kerr error to be used, got %q", got)
+	}
+}
+
+func TestDisplayOverride(t *testing.T) {
+	t.Cleanup(snapshotCatalogs())
+	AddMessages("en", map[int]string{96604: "Stock not enough", 30003: "No data found"})
+	stock := New(96604, "stock not enough").WithDisplay("库存不足")
+
+	// 首次设置的是默认显示信息，多语言目录优先
+	if got := stock.DisplayIn("en"); got != "Stock not enough" {
+		t.Errorf("expected catalog to win over the default display, got %q", got)
+	}
+	// 实例级覆盖优先于多语言目录
+	custom := stock.WithDisplay("仅剩 2 件").WithField("sku", "A1")
+	if got := custom.DisplayIn("en"); got != "仅剩 2 件" {
+		t.Errorf("expected per-instance display to win, got %q", got)
+	}
+	ctx := kctx.New()
+	ctx.Set(LocaleKey, "en")
+	if got := DisplayFor(ctx, NoData.WithDisplay("订单不存在")); got != "订单不存在" {
+		t.Errorf("expected per-instance display of a builtin code to win, got %q", got)
+	}
+	if got := DisplayFor(ctx, fmt.Errorf("load: %w", NoData)); got != "No data found" {
+		t.Errorf("expected wrapped NoData to use the catalog, got %q", got)
+	}
+	if got := Join(NoData).WithDisplay("批量失败").DisplayIn("en"); got != "批量失败" {
+		t.Errorf("expected MultiError display to win, got %q", got)
+	}
+}
@@ -45,7 +45,7 @@ func TestWrapUnwrap(t *testing.T) {
 		t.Errorf("expected errors.Is to detect base error")
 	}
 
-	unwrapped := err.Unwrap()
+	unwrapped := errors.Unwrap(err)
 	if unwrapped != baseErr {
 		t.Errorf("expected Unwrap() to return baseErr, got %v", unwrapped)
 	}
@@ -139,3 +139,163 @@ func TestChainWithDisplay(t *testing.T) {
 		t.Errorf("expected Error() to contain cause, got '%s'", err2.Error())
 	}
 }
+
+func TestJSONRoundTrip(t *testing.T) {
+	inner := DBError.Wrap(errors.New("connection refused")).WithStack()
+	err := NoData.Wrap(inner).WithStack()
+
+	data, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatalf("MarshalJSON failed: %v", marshalErr)
+	}
+
+	var result map[string]interface{}
+	if jsonErr := json.Unmarshal(data, &result); jsonErr != nil {
+		t.Fatalf("Unmarshal failed: %v", jsonErr)
+	}
+	if _, ok := result["cause"].(map[string]interface{}); !ok {
+		t.Fatalf("expected cause to be a nested JSON object, got %T", result["cause"])
+	}
+
+	remote, decodeErr := FromJSON(data)
+	if decodeErr != nil {
+		t.Fatalf("FromJSON failed: %v", decodeErr)
+	}
+	if !errors.Is(remote, NoData) || !errors.Is(remote, DBError) {
+		t.Errorf("expected decoded error to match NoData and DBError by code")
+	}
+	if remote.Error() != err.Error() {
+		t.Errorf("expected Error() %q, got %q", err.Error(), remote.Error())
+	}
+	if remote.Display() != NoData.Display() {
+		t.Errorf("expected display %q, got %q", NoData.Display(), remote.Display())
+	}
+	if !strings.Contains(remote.Stack(), "kerr_test.go") {
+		t.Errorf("expected remote stack frames to be kept, got %q", remote.Stack())
+	}
+
+	again, _ := json.Marshal(remote)
+	if string(again) != string(data) {
+		t.Errorf("expected re-marshaled JSON to be identical\nwant %s\ngot  %s", data, again)
+	}
+}
+
+func TestFromJSONInvalid(t *testing.T) {
+	if _, err := FromJSON([]byte(`{"code":"x"}`)); err == nil {
+		t.Errorf("expected error for invalid JSON")
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	base := DBError.WithField("table", "users")
+	err := NoData.Wrap(base).WithFields(map[string]any{"request_id": "req-1", "table": "orders"})
+
+	if len(DBError.Fields()) != 0 {
+		t.Errorf("WithField must not modify the original instance")
+	}
+	if got := base.Fields(); got["table"] != "users" {
+		t.Errorf("expected table=users, got %v", got)
+	}
+
+	fields := err.Fields()
+	if fields["request_id"] != "req-1" || fields["table"] != "orders" {
+		t.Errorf("expected outer fields to override inner fields, got %v", fields)
+	}
+
+	inner := DBError.WithField("user_id", 1001)
+	merged := NoData.Wrap(inner).WithField("request_id", "req-2").Fields()
+	if merged["user_id"] != 1001 || merged["request_id"] != "req-2" {
+		t.Errorf("expected fields merged up the cause chain, got %v", merged)
+	}
+
+	output := fmt.Sprintf("%+v", err)
+	if !strings.Contains(output, "fields: request_id=req-1 table=orders") || !strings.Contains(output, "fields: table=users") {
+		t.Errorf("expected %%+v output to contain fields, got: %s", output)
+	}
+
+	remote, decodeErr := FromJSON([]byte(err.ToJSON()))
+	if decodeErr != nil {
+		t.Fatalf("FromJSON failed: %v", decodeErr)
+	}
+	if got := remote.Fields(); got["request_id"] != "req-1" || got["table"] != "orders" {
+		t.Errorf("expected fields to round-trip through JSON, got %v", got)
+	}
+}
+
+func TestNewf(t *testing.T) {
+	err := Newf(9000, "user %d not found", 1001)
+	if err.Error() != "user 1001 not found" || err.Code() != 9000 {
+		t.Errorf("unexpected Newf result: %d %q", err.Code(), err.Error())
+	}
+}
+
+func TestWithMessage(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := DBError.Wrap(cause).WithMessage("query user %d", 1001).WithMessage("load profile")
+
+	if got := err.Error(); got != "load profile: query user 1001: database error: connection refused" {
+		t.Errorf("expected Error() to print the full annotation chain, got %q", got)
+	}
+	if err.Code() != DBError.Code() || err.Display() != DBError.Display() {
+		t.Errorf("expected code and display of the sentinel to be kept")
+	}
+	if !errors.Is(err, DBError) || !errors.Is(err, cause) {
+		t.Errorf("expected errors.Is to keep working after annotation")
+	}
+	if DBError.Error() != "database error" {
+		t.Errorf("WithMessage must not modify the sentinel, got %q", DBError.Error())
+	}
+
+	// 附加信息后仍可继续包装 cause
+	rewrapped := DBError.WithMessage("query user").Wrap(cause)
+	if got := rewrapped.Error(); got != "query user: database error: connection refused" {
+		t.Errorf("unexpected Error() after Wrap: %q", got)
+	}
+
+	output := fmt.Sprintf("%+v", err)
+	if !strings.Contains(output, "load profile: query user 1001: database error (code=40000") {
+		t.Errorf("expected %%+v output to contain annotations, got: %s", output)
+	}
+
+	remote, decodeErr := FromJSON([]byte(err.ToJSON()))
+	if decodeErr != nil {
+		t.Fatalf("FromJSON failed: %v", decodeErr)
+	}
+	if remote.Error() != err.Error() {
+		t.Errorf("expected annotations to round-trip through JSON, got %q", remote.Error())
+	}
+}
+
+func TestWrapf(t *testing.T) {
+	if Wrapf(nil, "ignored") != nil {
+		t.Errorf("expected Wrapf(nil) to return nil")
+	}
+
+	err := Wrapf(NoData, "order %s", "A001")
+	if !errors.Is(err, NoData) || err.Error() != "order A001: no data" {
+		t.Errorf("unexpected Wrapf result: %v", err)
+	}
+
+	plain := errors.New("disk full")
+	err = Wrapf(plain, "write %s", "a.log")
+	if err.Code() != SystemError.Code() || !errors.Is(err, plain) {
+		t.Errorf("expected foreign error to be wrapped as SystemError, got %d", err.Code())
+	}
+	if err.Error() != "write a.log: system error: disk full" {
+		t.Errorf("unexpected Error(): %q", err.Error())
+	}
+
+	load := fmt.Errorf("load: %w", NoData.WithField("table", "orders"))
+	wrapped := Wrapf(load, "order %s", "A001")
+	if wrapped.Code() != NoData.Code() || !errors.Is(wrapped, NoData) || wrapped.Display() != NoData.Display() {
+		t.Errorf("expected wrapped kerr error to keep its code, got %d %q", wrapped.Code(), wrapped.Display())
+	}
+	if wrapped.Error() != "order A001: "+load.Error() || wrapped.Fields()["table"] != "orders" {
+		t.Errorf("unexpected Wrapf result for wrapped kerr error: %q %v", wrapped.Error(), wrapped.Fields())
+	}
+
+	multi := Wrapf(Join(NoData, TimeoutError), "batch %d", 3)
+	if multi.Error() != "batch 3: no data; operation timeout" {
+		t.Errorf("unexpected MultiError annotation: %q", multi.Error())
+	}
+}
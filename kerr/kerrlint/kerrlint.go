@@ -0,0 +1,515 @@
+// Package kerrlint 提供检查 kerr 误用的静态分析器
+//
+//   - CompareAnalyzer：使用 == / != 或 switch 将错误与 kerr 哨兵错误比较。
+//     哨兵错误经 Wrap、WithDisplay、WithStack 等派生后是新的实例，按指针比较不再相等，应使用 errors.Is
+//   - DiscardAnalyzer：丢弃 WithStack、WithField、Wrap 等方法的返回值。
+//     kerr 错误不可变，这些方法返回新实例，不修改原错误
+//   - CodeAnalyzer：直接调用 kerr.New 复用已注册的错误码，但错误信息与注册时不同。
+//     包级变量中由 kerr.New 定义的错误视为已注册的规范错误，应从规范错误派生
+//
+// 有意为之的用法（如测试中断言返回的是同一实例）可在所在行或上一行添加注释抑制诊断，
+// 可指定分析器名称，多个名称以逗号分隔，其后为说明：
+//
+//	if got != kerr.NoData { //kerrlint:ignore kerrcompare Translate 原样返回 kerr 错误
+//
+// 命令行工具见 cmd/kerrlint。
+package kerrlint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// kerrPath kerr 包的导入路径
+const kerrPath = "github.com/kearth/klib/kerr"
+
+// 分析器名称，用于 ignoreDirective 指定分析器
+const (
+	compareName = "kerrcompare"
+	discardName = "kerrdiscard"
+	codeName    = "kerrcode"
+)
+
+// Analyzers 全部分析器
+var Analyzers = []*analysis.Analyzer{CompareAnalyzer, DiscardAnalyzer, CodeAnalyzer}
+
+// CompareAnalyzer 检查与 kerr 哨兵错误的 == / != 比较
+var CompareAnalyzer = &analysis.Analyzer{
+	Name:     compareName,
+	Doc:      "report comparisons against kerr sentinel errors using == or != instead of errors.Is",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runCompare,
+}
+
+// DiscardAnalyzer 检查被丢弃的 With*、Wrap 返回值
+var DiscardAnalyzer = &analysis.Analyzer{
+	Name:     discardName,
+	Doc:      "report discarded results of kerr With* and Wrap methods, which return a new error",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runDiscard,
+}
+
+// CodeAnalyzer 检查复用已注册错误码但错误信息不同的 kerr.New 调用
+var CodeAnalyzer = &analysis.Analyzer{
+	Name:      codeName,
+	Doc:       "report kerr.New calls that reuse a registered error code with a different message",
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	Run:       runCode,
+	FactTypes: []analysis.Fact{new(codeFact)},
+}
+
+// ignoreDirective 抑制诊断的注释前缀
+const ignoreDirective = "//kerrlint:ignore"
+
+// suppressions 文件中 ignoreDirective 所在的行，值为指定的分析器名称，为空时对全部分析器生效
+type suppressions map[string]map[int][]string
+
+// newSuppressions 收集当前包中的 ignoreDirective 注释
+func newSuppressions(pass *analysis.Pass) suppressions {
+	s := make(suppressions)
+	for _, file := range pass.Files {
+		for _, group := range file.Comments {
+			for _, c := range group.List {
+				rest, ok := strings.CutPrefix(c.Text, ignoreDirective)
+				if !ok || rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+					continue
+				}
+				pos := pass.Fset.Position(c.Pos())
+				if s[pos.Filename] == nil {
+					s[pos.Filename] = make(map[int][]string)
+				}
+				s[pos.Filename][pos.Line] = analyzerNames(rest)
+			}
+		}
+	}
+	return s
+}
+
+// analyzerNames 解析 ignoreDirective 之后的分析器名称，第一个字段不全是分析器名称时视为说明，返回空
+func analyzerNames(rest string) []string {
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil
+	}
+	names := strings.Split(fields[0], ",")
+	for _, name := range names {
+		if !isAnalyzerName(name) {
+			return nil
+		}
+	}
+	return names
+}
+
+// isAnalyzerName 判断是否为本包的分析器名称
+func isAnalyzerName(name string) bool {
+	switch name {
+	case compareName, discardName, codeName:
+		return true
+	}
+	return false
+}
+
+// report 报告诊断，所在行或上一行有对当前分析器生效的 ignoreDirective 时忽略
+func (s suppressions) report(pass *analysis.Pass, d analysis.Diagnostic) {
+	pos := pass.Fset.Position(d.Pos)
+	for _, line := range []int{pos.Line, pos.Line - 1} {
+		names, ok := s[pos.Filename][line]
+		if ok && (len(names) == 0 || slices.Contains(names, pass.Analyzer.Name)) {
+			return
+		}
+	}
+	pass.Report(d)
+}
+
+// isKerrType 判断类型是否为 kerr.Error、*kerr.KError 或 *kerr.MultiError
+func isKerrType(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != kerrPath {
+		return false
+	}
+	switch named.Obj().Name() {
+	case "Error", "KError", "MultiError":
+		return true
+	}
+	return false
+}
+
+// sentinelOf 返回表达式引用的哨兵错误，即 kerr 错误类型的包级变量
+func sentinelOf(info *types.Info, expr ast.Expr) *types.Var {
+	var id *ast.Ident
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		id = e
+	case *ast.SelectorExpr:
+		id = e.Sel
+	default:
+		return nil
+	}
+	v, ok := info.Uses[id].(*types.Var)
+	if !ok || v.IsField() || v.Pkg() == nil || v.Parent() != v.Pkg().Scope() || !isKerrType(v.Type()) {
+		return nil
+	}
+	return v
+}
+
+// isNil 判断表达式是否为 nil
+func isNil(info *types.Info, expr ast.Expr) bool {
+	tv, ok := info.Types[expr]
+	return ok && tv.IsNil()
+}
+
+// sentinelName 返回哨兵错误的限定名，如 kerr.NoData
+func sentinelName(pass *analysis.Pass, v *types.Var) string {
+	if v.Pkg() == pass.Pkg {
+		return v.Name()
+	}
+	return v.Pkg().Name() + "." + v.Name()
+}
+
+// registryResults 返回由 kerr.Lookup、kerr.Registered 的结果赋值的变量
+// 注册表返回的是注册的实例本身，与哨兵错误按指针比较是有意的同一性判断
+func registryResults(pass *analysis.Pass) map[types.Object]bool {
+	objs := make(map[types.Object]bool)
+	record := func(lhs ast.Expr, rhs ast.Expr) {
+		call, ok := ast.Unparen(rhs).(*ast.CallExpr)
+		if !ok || !isKerrFunc(pass.TypesInfo, call, "Lookup") && !isKerrFunc(pass.TypesInfo, call, "Registered") {
+			return
+		}
+		if id, ok := lhs.(*ast.Ident); ok {
+			if obj := pass.TypesInfo.ObjectOf(id); obj != nil {
+				objs[obj] = true
+			}
+		}
+	}
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.ValueSpec)(nil)}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Rhs) == 1 && len(n.Lhs) > 0 {
+				record(n.Lhs[0], n.Rhs[0])
+			}
+		case *ast.ValueSpec:
+			if len(n.Values) == 1 && len(n.Names) > 0 {
+				record(n.Names[0], n.Values[0])
+			}
+		}
+	})
+	return objs
+}
+
+// isRegistryResult 判断表达式是否为注册表返回的错误，如 Lookup 的结果或 Registered 结果的元素
+func isRegistryResult(info *types.Info, objs map[types.Object]bool, expr ast.Expr) bool {
+	expr = ast.Unparen(expr)
+	if index, ok := expr.(*ast.IndexExpr); ok {
+		expr = ast.Unparen(index.X)
+	}
+	id, ok := expr.(*ast.Ident)
+	return ok && objs[info.ObjectOf(id)]
+}
+
+func runCompare(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	filter := []ast.Node{(*ast.File)(nil), (*ast.BinaryExpr)(nil), (*ast.SwitchStmt)(nil)}
+	registry := registryResults(pass)
+	ignore := newSuppressions(pass)
+
+	var file *ast.File
+	insp.Preorder(filter, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.File:
+			file = n
+		case *ast.BinaryExpr:
+			if n.Op != token.EQL && n.Op != token.NEQ {
+				return
+			}
+			x, y := n.X, n.Y
+			s := sentinelOf(pass.TypesInfo, y)
+			if s == nil {
+				x, y = y, x
+				s = sentinelOf(pass.TypesInfo, y)
+			}
+			if s == nil || isNil(pass.TypesInfo, x) || sentinelOf(pass.TypesInfo, x) != nil || isRegistryResult(pass.TypesInfo, registry, x) {
+				return
+			}
+			ignore.report(pass, analysis.Diagnostic{
+				Pos:     n.Pos(),
+				End:     n.End(),
+				Message: "comparing with " + sentinelName(pass, s) + " using " + n.Op.String() + " breaks once the error is wrapped or derived; use errors.Is",
+				SuggestedFixes: []analysis.SuggestedFix{
+					isFix(pass, file, n, x, y, n.Op == token.NEQ),
+				},
+			})
+		case *ast.SwitchStmt:
+			if n.Tag == nil || isNil(pass.TypesInfo, n.Tag) || !isErrorType(pass.TypesInfo.TypeOf(n.Tag)) {
+				return
+			}
+			for _, stmt := range n.Body.List {
+				for _, expr := range stmt.(*ast.CaseClause).List {
+					if s := sentinelOf(pass.TypesInfo, expr); s != nil {
+						ignore.report(pass, analysis.Diagnostic{
+							Pos:     expr.Pos(),
+							Message: "switch case " + sentinelName(pass, s) + " compares errors using ==, which breaks once the error is wrapped or derived; use errors.Is",
+						})
+					}
+				}
+			}
+		}
+	})
+	return nil, nil
+}
+
+// isErrorType 判断类型是否实现了 error
+func isErrorType(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	errType := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	return types.Implements(t, errType)
+}
+
+// isFix 返回将比较改写为 errors.Is 的修复，文件未导入 errors 时同时添加导入
+func isFix(pass *analysis.Pass, file *ast.File, n *ast.BinaryExpr, err, target ast.Expr, negate bool) analysis.SuggestedFix {
+	name, imported := importName(file, "errors")
+	call := name + ".Is(" + types.ExprString(err) + ", " + types.ExprString(target) + ")"
+	if negate {
+		call = "!" + call
+	}
+	edits := []analysis.TextEdit{{Pos: n.Pos(), End: n.End(), NewText: []byte(call)}}
+	if !imported {
+		edits = append(edits, analysis.TextEdit{
+			Pos:     file.Name.End(),
+			End:     file.Name.End(),
+			NewText: []byte("\n\nimport \"errors\""),
+		})
+	}
+	return analysis.SuggestedFix{Message: "Use errors.Is", TextEdits: edits}
+}
+
+// importName 返回文件中导入 path 所用的包名
+func importName(file *ast.File, path string) (string, bool) {
+	for _, spec := range file.Imports {
+		if p, _ := strconv.Unquote(spec.Path.Value); p != path {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name, true
+		}
+		return path[strings.LastIndex(path, "/")+1:], true
+	}
+	return path[strings.LastIndex(path, "/")+1:], false
+}
+
+func runDiscard(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ignore := newSuppressions(pass)
+	filter := []ast.Node{(*ast.ExprStmt)(nil), (*ast.AssignStmt)(nil)}
+
+	insp.Preorder(filter, func(n ast.Node) {
+		var call *ast.CallExpr
+		switch n := n.(type) {
+		case *ast.ExprStmt:
+			call, _ = ast.Unparen(n.X).(*ast.CallExpr)
+		case *ast.AssignStmt:
+			if len(n.Lhs) != 1 || len(n.Rhs) != 1 {
+				return
+			}
+			if id, ok := n.Lhs[0].(*ast.Ident); !ok || id.Name != "_" {
+				return
+			}
+			call, _ = ast.Unparen(n.Rhs[0]).(*ast.CallExpr)
+		}
+		if name, ok := builderMethod(pass.TypesInfo, call); ok {
+			ignore.report(pass, analysis.Diagnostic{
+				Pos:     call.Pos(),
+				Message: fmt.Sprintf("result of %s is discarded; kerr errors are immutable and %s returns a new error", name, name),
+			})
+		}
+	})
+	return nil, nil
+}
+
+// builderMethod 判断调用是否为返回新实例的 kerr 方法，如 WithStack、WithField、Wrap
+func builderMethod(info *types.Info, call *ast.CallExpr) (string, bool) {
+	if call == nil {
+		return "", false
+	}
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	selection, ok := info.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal {
+		return "", false
+	}
+	fn := selection.Obj().(*types.Func)
+	if fn.Pkg() == nil || fn.Pkg().Path() != kerrPath || !isKerrType(selection.Recv()) {
+		return "", false
+	}
+	if !strings.HasPrefix(fn.Name(), "With") && fn.Name() != "Wrap" {
+		return "", false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Results().Len() != 1 || !isKerrType(sig.Results().At(0).Type()) {
+		return "", false
+	}
+	return fn.Name(), true
+}
+
+// codeFact 哨兵错误的错误码和错误信息，在包之间传递
+type codeFact struct {
+	Code int
+	Msg  string
+}
+
+// AFact 实现 analysis.Fact
+func (*codeFact) AFact() {}
+
+// String 返回事实的文本表示，用于测试
+func (f *codeFact) String() string {
+	return "code " + strconv.Itoa(f.Code) + " " + strconv.Quote(f.Msg)
+}
+
+// sentinelCode 已注册的错误码对应的哨兵错误
+type sentinelCode struct {
+	obj types.Object
+	msg string
+}
+
+func runCode(pass *analysis.Pass) (any, error) {
+	codes := make(map[int]sentinelCode)
+	for _, f := range pass.AllObjectFacts() {
+		fact := f.Fact.(*codeFact)
+		if _, ok := codes[fact.Code]; !ok {
+			codes[fact.Code] = sentinelCode{obj: f.Object, msg: fact.Msg}
+		}
+	}
+
+	// 当前包的哨兵错误：包级变量的初始化表达式以 kerr.New 为根
+	owners := make(map[*ast.CallExpr]types.Object)
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				if len(vs.Names) != len(vs.Values) {
+					continue
+				}
+				for i, name := range vs.Names {
+					obj := pass.TypesInfo.Defs[name]
+					root := rootNew(pass.TypesInfo, vs.Values[i])
+					if obj == nil || root == nil || !isKerrType(obj.Type()) {
+						continue
+					}
+					code, msg, ok := newArgs(pass.TypesInfo, root)
+					if !ok {
+						continue
+					}
+					owners[root] = obj
+					if _, ok := codes[code]; !ok {
+						codes[code] = sentinelCode{obj: obj, msg: msg}
+						pass.ExportObjectFact(obj, &codeFact{Code: code, Msg: msg})
+					}
+				}
+			}
+		}
+	}
+
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ignore := newSuppressions(pass)
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		if !isKerrFunc(pass.TypesInfo, call, "New") {
+			return
+		}
+		code, msg, ok := newArgs(pass.TypesInfo, call)
+		if !ok {
+			return
+		}
+		s, ok := codes[code]
+		if !ok || s.obj == owners[call] || s.msg == msg {
+			return
+		}
+		name := s.obj.Name()
+		if s.obj.Pkg() != pass.Pkg {
+			name = s.obj.Pkg().Name() + "." + name
+		}
+		ignore.report(pass, analysis.Diagnostic{
+			Pos: call.Pos(),
+			Message: fmt.Sprintf("code %d is registered as %s with message %q; derive from %s (e.g. %s.WithMessage) so errors.Is and the catalog stay consistent",
+				code, name, s.msg, name, name),
+		})
+	})
+	return nil, nil
+}
+
+// rootNew 返回初始化表达式的根 kerr.New 调用，跳过 With* 方法链和 Register/MustRegister
+func rootNew(info *types.Info, expr ast.Expr) *ast.CallExpr {
+	for {
+		call, ok := ast.Unparen(expr).(*ast.CallExpr)
+		if !ok {
+			return nil
+		}
+		switch {
+		case isKerrFunc(info, call, "New"):
+			return call
+		case isKerrFunc(info, call, "Register"), isKerrFunc(info, call, "MustRegister"):
+			if len(call.Args) == 0 {
+				return nil
+			}
+			expr = call.Args[0]
+		default:
+			if _, ok := builderMethod(info, call); !ok {
+				return nil
+			}
+			expr = call.Fun.(*ast.SelectorExpr).X
+		}
+	}
+}
+
+// isKerrFunc 判断调用是否为 kerr 包的指定函数
+func isKerrFunc(info *types.Info, call *ast.CallExpr, name string) bool {
+	var id *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return false
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	return ok && fn.Name() == name && fn.Pkg() != nil && fn.Pkg().Path() == kerrPath &&
+		fn.Type().(*types.Signature).Recv() == nil
+}
+
+// newArgs 返回 kerr.New 调用的常量错误码和错误信息
+func newArgs(info *types.Info, call *ast.CallExpr) (int, string, bool) {
+	if len(call.Args) != 2 {
+		return 0, "", false
+	}
+	code, msg := info.Types[call.Args[0]].Value, info.Types[call.Args[1]].Value
+	if code == nil || msg == nil || code.Kind() != constant.Int || msg.Kind() != constant.String {
+		return 0, "", false
+	}
+	c, ok := constant.Int64Val(code)
+	if !ok {
+		return 0, "", false
+	}
+	return int(c), constant.StringVal(msg), true
+}
@@ -0,0 +1,19 @@
+package kerrlint
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestCompareAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), CompareAnalyzer, "compare")
+}
+
+func TestDiscardAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), DiscardAnalyzer, "discard")
+}
+
+func TestCodeAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), CodeAnalyzer, "orderr", "code")
+}
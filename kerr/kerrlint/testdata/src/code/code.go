@@ -0,0 +1,28 @@
+package code
+
+import (
+	"github.com/kearth/klib/kerr"
+
+	"orderr"
+)
+
+var (
+	ErrConflict = kerr.New(60001, "order missing")  // want `code 60001 is registered as orderr.OrderNotFound with message "order not found"`
+	ErrPayment  = kerr.New(61001, "payment failed") // want ErrPayment:`code 61001 "payment failed"`
+)
+
+func load(id int) error {
+	if id == 0 {
+		return kerr.New(30002, "dependency not ready") // want `code 30002 is registered as kerr.DependencyMissing with message "dependency missing"; derive from kerr.DependencyMissing \(e.g. kerr.DependencyMissing.WithMessage\)`
+	}
+	if id == 1 {
+		return kerr.New(61001, "payment declined") // want `code 61001 is registered as ErrPayment`
+	}
+	if id == 2 {
+		return kerr.New(60002, "order closed")
+	}
+	if id == 3 {
+		return kerr.New(69999, "unregistered")
+	}
+	return orderr.OrderClosed
+}
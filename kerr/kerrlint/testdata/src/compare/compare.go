@@ -0,0 +1,51 @@
+package compare
+
+import (
+	"errors"
+
+	"github.com/kearth/klib/kerr"
+)
+
+var ErrLocal = kerr.New(60001, "local")
+
+func check(err error, kerrErr kerr.Error) bool {
+	if err == kerr.NoData { // want `comparing with kerr.NoData using == breaks once the error is wrapped or derived; use errors.Is`
+		return true
+	}
+	if kerrErr != kerr.DependencyMissing { // want `comparing with kerr.DependencyMissing using != breaks`
+		return false
+	}
+	if ErrLocal == err { // want `comparing with ErrLocal using ==`
+		return true
+	}
+	switch err {
+	case nil:
+	case kerr.SystemError: // want `switch case kerr.SystemError compares errors using ==`
+	}
+	if err == nil || errors.Is(err, kerr.NoData) {
+		return true
+	}
+	local := kerr.NoData
+	return local == nil
+}
+
+func registry() bool {
+	registered, ok := kerr.Lookup(30003)
+	all := kerr.Registered()
+	return ok && registered == kerr.NoData && all[0] != kerr.SystemError
+}
+
+func suppressed(err error) bool {
+	if err == kerr.NoData { //kerrlint:ignore kerrcompare Cross must return the same instance
+		return true
+	}
+	//kerrlint:ignore
+	if err != kerr.SystemError {
+		return false
+	}
+	//kerrlint:ignore kerrdiscard,kerrcode
+	if err == kerr.DependencyMissing { // want `comparing with kerr.DependencyMissing using ==`
+		return true
+	}
+	return false
+}
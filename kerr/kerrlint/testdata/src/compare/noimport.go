@@ -0,0 +1,7 @@
+package compare
+
+import "github.com/kearth/klib/kerr"
+
+func missing(err kerr.Error) bool {
+	return err == kerr.DependencyMissing // want `comparing with kerr.DependencyMissing using ==`
+}
@@ -0,0 +1,20 @@
+package discard
+
+import (
+	"io"
+
+	"github.com/kearth/klib/kerr"
+)
+
+func build() kerr.Error {
+	err := kerr.NoData.Wrap(io.EOF)
+	err.WithStack()                       // want `result of WithStack is discarded; kerr errors are immutable and WithStack returns a new error`
+	err.WithField("id", 1)                // want `result of WithField is discarded`
+	_ = kerr.SystemError.Wrap(io.EOF)     // want `result of Wrap is discarded`
+	kerr.New(60001, "x").WithDisplay("x") // want `result of WithDisplay is discarded`
+
+	err = err.WithStack()
+	_ = err.Error()
+	_ = err.Fields()
+	return err.WithMessage("load")
+}
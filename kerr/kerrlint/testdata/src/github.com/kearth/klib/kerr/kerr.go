@@ -0,0 +1,41 @@
+// Package kerr 测试用的 kerr 桩包，仅包含分析器用到的 API
+package kerr
+
+type Error interface {
+	error
+	Code() int
+	WithMessage(format string, args ...any) Error
+	WithField(key string, val any) Error
+	WithDisplay(display string) Error
+	WithStack(skip ...int) Error
+	Wrap(err error) Error
+	Fields() map[string]any
+}
+
+type KError struct {
+	code int
+	msg  string
+}
+
+func New(code int, msg string) *KError { return &KError{code: code, msg: msg} }
+
+func MustRegister(err Error) Error { return err }
+
+func Lookup(code int) (Error, bool) { return nil, false }
+
+func Registered() []Error { return nil }
+
+func (e *KError) Error() string                                { return e.msg }
+func (e *KError) Code() int                                    { return e.code }
+func (e *KError) WithMessage(format string, args ...any) Error { return e }
+func (e *KError) WithField(key string, val any) Error          { return e }
+func (e *KError) WithDisplay(display string) Error             { return e }
+func (e *KError) WithStack(skip ...int) Error                  { return e }
+func (e *KError) Wrap(err error) Error                         { return e }
+func (e *KError) Fields() map[string]any                       { return nil }
+
+var (
+	SystemError       = New(10000, "system error").WithDisplay("系统错误，请稍后重试")
+	DependencyMissing = New(30002, "dependency missing").WithDisplay("依赖缺失或未初始化")
+	NoData            = New(30003, "no data").WithDisplay("没有符合条件的数据")
+)
@@ -0,0 +1,8 @@
+package orderr
+
+import "github.com/kearth/klib/kerr"
+
+var (
+	OrderNotFound = kerr.MustRegister(kerr.New(60001, "order not found").WithDisplay("订单不存在")) // want OrderNotFound:`code 60001 "order not found"`
+	OrderClosed   = kerr.New(60002, "order closed")                                            // want OrderClosed:`code 60002 "order closed"`
+)
@@ -0,0 +1,37 @@
+// Package kerrlog 提供基于 klog 的 kerr 错误上报器和 panic 回调。
+//
+// kerr 只定义 Reporter 接口和 PanicHook，不依赖日志实现；需要将错误写入 klog 日志时使用本包：
+//
+//	kerr.SetReporter(kerrlog.NewReporter())
+//	kerr.SetPanicHook(kerrlog.LogPanic)
+package kerrlog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kearth/klib/kerr"
+	"github.com/kearth/klib/klog"
+)
+
+// reporter 通过 klog 输出上报的错误
+type reporter struct{}
+
+// NewReporter 创建通过 klog 以 Error 级别输出错误及堆栈的上报器
+func NewReporter() kerr.Reporter {
+	return reporter{}
+}
+
+// Report 实现 kerr.Reporter 接口
+func (reporter) Report(ctx context.Context, event kerr.ReportEvent) {
+	msg := fmt.Sprintf("[report] fingerprint=%s", event.Fingerprint)
+	if event.Suppressed > 0 {
+		msg += fmt.Sprintf(" suppressed=%d", event.Suppressed)
+	}
+	klog.Error(ctx, msg, fmt.Sprintf("%+v", event.Err))
+}
+
+// LogPanic kerr.PanicHook 的实现：通过 klog 输出错误及堆栈，日志带有 ctx 中的 kctx TraceID
+func LogPanic(ctx context.Context, err kerr.Error) {
+	klog.Error(ctx, fmt.Sprintf("%+v", err))
+}
@@ -0,0 +1,82 @@
+package kerrlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/kearth/klib/kctx"
+	"github.com/kearth/klib/kerr"
+	"github.com/kearth/klib/klog"
+)
+
+// captureLogs 捕获 klog 的日志内容，测试结束后恢复默认处理器
+func captureLogs(t *testing.T) func() []string {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		logs []string
+	)
+	old := glog.GetDefaultHandler()
+	glog.SetDefaultHandler(func(ctx context.Context, in *glog.HandlerInput) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, fmt.Sprint(in.Values...))
+	})
+	t.Cleanup(func() { glog.SetDefaultHandler(old) })
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), logs...)
+	}
+}
+
+func TestReporter(t *testing.T) {
+	logs := captureLogs(t)
+
+	err := kerr.SystemError.Wrap(errors.New("disk full")).WithField("volume", "/data")
+	NewReporter().Report(kctx.New(), kerr.ReportEvent{Err: err, Fingerprint: "abc", Suppressed: 2})
+
+	got := logs()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 log, got %v", got)
+	}
+	for _, want := range []string{"fingerprint=abc", "suppressed=2", "Caused by: disk full", "volume=/data"} {
+		if !strings.Contains(got[0], want) {
+			t.Errorf("expected log to contain %q, got %s", want, got[0])
+		}
+	}
+}
+
+func TestLogPanic(t *testing.T) {
+	logs := captureLogs(t)
+	kerr.SetPanicHook(LogPanic)
+	t.Cleanup(func() { kerr.SetPanicHook(nil) })
+
+	err := kerr.SafeCall(func() error { panic("boom") })
+	if !errors.Is(err, kerr.PanicError) {
+		t.Fatalf("expected PanicError, got %v", err)
+	}
+	got := logs()
+	if len(got) != 1 || !strings.Contains(got[0], "panic: boom") || !strings.Contains(got[0], "kerrlog_test.go") {
+		t.Errorf("expected panic log with stack, got %v", got)
+	}
+}
+
+func TestKlogRedactsErrors(t *testing.T) {
+	logs := captureLogs(t)
+
+	klog.Error(kctx.New(), "query: ", kerr.DBError.Wrap(errors.New("dial mysql://root:hunter2@db:3306")).WithField("token", "abc"))
+
+	got := logs()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 log, got %v", got)
+	}
+	if strings.Contains(got[0], "hunter2") || strings.Contains(got[0], "token=abc") || !strings.Contains(got[0], "root:***@db") {
+		t.Errorf("expected klog to redact kerr errors, got %s", got[0])
+	}
+}
@@ -0,0 +1,92 @@
+package kgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/kearth/klib/kerr"
+	"google.golang.org/grpc"
+)
+
+// UnaryServerInterceptor 服务端一元拦截器
+// 将处理函数返回的错误转换为 gRPC status，panic 转换为 PanicError，错误（包括 panic）在此通过 kerr.Report 上报一次
+//
+//	grpc.NewServer(grpc.ChainUnaryInterceptor(kgrpc.UnaryServerInterceptor()))
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if err != nil {
+				kerr.Report(ctx, err)
+				err = ToStatusContext(ctx, err).Err()
+			}
+		}()
+		defer kerr.RecoverContext(ctx, &err)
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 服务端流式拦截器
+// 将处理函数返回的错误转换为 gRPC status，panic 转换为 PanicError，错误（包括 panic）在此通过 kerr.Report 上报一次
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx := ss.Context()
+		defer func() {
+			if err != nil {
+				kerr.Report(ctx, err)
+				err = ToStatusContext(ctx, err).Err()
+			}
+		}()
+		defer kerr.RecoverContext(ctx, &err)
+		return handler(srv, ss)
+	}
+}
+
+// UnaryClientInterceptor 客户端一元拦截器，将服务端返回的 status 还原为 kerr 错误
+//
+//	grpc.NewClient(target, grpc.WithChainUnaryInterceptor(kgrpc.UnaryClientInterceptor()))
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return FromError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamClientInterceptor 客户端流式拦截器，将建立流及收发消息时的 status 还原为 kerr 错误
+// 流正常结束时的 io.EOF 原样返回
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, FromError(err)
+		}
+		return &clientStream{ClientStream: cs}, nil
+	}
+}
+
+// clientStream 还原收发消息错误的客户端流
+type clientStream struct {
+	grpc.ClientStream
+}
+
+// SendMsg 发送消息
+func (s *clientStream) SendMsg(m any) error {
+	return streamError(s.ClientStream.SendMsg(m))
+}
+
+// RecvMsg 接收消息
+func (s *clientStream) RecvMsg(m any) error {
+	return streamError(s.ClientStream.RecvMsg(m))
+}
+
+// CloseSend 关闭发送方向
+func (s *clientStream) CloseSend() error {
+	return streamError(s.ClientStream.CloseSend())
+}
+
+// streamError 还原流错误，io.EOF 表示流结束，不做转换
+func streamError(err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+	return FromError(err)
+}
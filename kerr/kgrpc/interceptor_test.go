@@ -0,0 +1,185 @@
+package kgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kearth/klib/kerr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthServer 按 service 名称返回不同错误的测试服务
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+}
+
+func (healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	switch req.GetService() {
+	case "quota":
+		return nil, kerr.QuotaExceeded.WithField("user", "u1").WithDetails(kerr.QuotaFailure{Subject: "u1", Limit: 10})
+	case "panic":
+		var m map[string]int
+		m["x"] = 1
+	case "plain":
+		return nil, errors.New("boom")
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (healthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+		return err
+	}
+	switch req.GetService() {
+	case "down":
+		return kerr.ServiceNotReady.WithMessage("watch %s", req.GetService())
+	case "panic":
+		panic("watch failed")
+	}
+	return nil
+}
+
+// dial 启动 bufconn 服务端并返回带有拦截器的客户端
+func dial(t *testing.T, clientInterceptors bool) grpc_health_v1.HealthClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor()),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, healthServer{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	opts := []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+	if clientInterceptors {
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(UnaryClientInterceptor()),
+			grpc.WithChainStreamInterceptor(StreamClientInterceptor()),
+		)
+	}
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	setPublicFields(t, "user")
+	client := dial(t, true)
+	ctx := context.Background()
+
+	if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "quota"})
+	if !errors.Is(err, kerr.QuotaExceeded) {
+		t.Fatalf("expected QuotaExceeded, got %v", err)
+	}
+	var e kerr.Error
+	if !errors.As(err, &e) || e.Fields()["user"] != "u1" || e.Display() != kerr.QuotaExceeded.Display() {
+		t.Errorf("expected fields and display to be restored, got %v", err)
+	}
+	if v := kerr.DetailsOf[kerr.QuotaFailure](err); len(v) != 1 || v[0].Limit != 10 {
+		t.Errorf("expected quota failure detail, got %v", v)
+	}
+
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "panic"})
+	if !errors.Is(err, kerr.PanicError) {
+		t.Errorf("expected PanicError, got %v", err)
+	}
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "plain"})
+	if !errors.Is(err, kerr.SystemError) {
+		t.Errorf("expected SystemError, got %v", err)
+	}
+}
+
+func TestServerInterceptorReportsOnce(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		reported []int
+	)
+	kerr.SetReporter(kerr.ReporterFunc(func(_ context.Context, event kerr.ReportEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, event.Code)
+	}))
+	kerr.SetDedupWindow(0)
+	t.Cleanup(kerr.ResetReporting)
+
+	client := dial(t, true)
+	_, _ = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "panic"})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := kerr.FlushReports(ctx); err != nil {
+		t.Fatalf("FlushReports failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 1 || reported[0] != kerr.PanicError.Code() {
+		t.Errorf("expected the panic to be reported once, got %v", reported)
+	}
+}
+
+func TestUnaryServerInterceptorWithoutClient(t *testing.T) {
+	client := dial(t, false)
+
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "quota"})
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted status, got %v", err)
+	}
+	if !errors.Is(FromStatus(st), kerr.QuotaExceeded) {
+		t.Errorf("expected status to carry the kerr code")
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	client := dial(t, true)
+	ctx := context.Background()
+
+	recvAll := func(service string) error {
+		stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		for {
+			if _, err := stream.Recv(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := recvAll(""); err != io.EOF {
+		t.Errorf("expected io.EOF at end of stream, got %v", err)
+	}
+	err := recvAll("down")
+	if !errors.Is(err, kerr.ServiceNotReady) {
+		t.Fatalf("expected ServiceNotReady, got %v", err)
+	}
+	if err.Error() != kerr.ServiceNotReady.Display() {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if err := recvAll("panic"); !errors.Is(err, kerr.PanicError) {
+		t.Errorf("expected PanicError, got %v", err)
+	}
+}
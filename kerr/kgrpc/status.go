@@ -0,0 +1,262 @@
+// Package kgrpc 提供 kerr 错误与 gRPC status 之间的转换，以及自动转换错误的服务端、客户端拦截器。
+//
+// kerr 错误码、显示信息、结构化字段及错误详情通过 status 的 ErrorInfo、LocalizedMessage 详情传递，
+// 客户端可通过 FromStatus 还原为 kerr 错误，并使用 errors.Is 与本地错误码常量比较。
+package kgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/kearth/klib/kerr"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain ErrorInfo 的 domain，用于识别 kerr 生成的 status
+const ErrorDomain = "kerr"
+
+// ErrorInfo 元数据的键
+const (
+	MetadataCode    = "code"    // kerr 错误码
+	MetadataFields  = "fields"  // 结构化字段，JSON 对象
+	MetadataDetails = "details" // kerr 错误详情，JSON 数组，见 kerr.MarshalDetails
+)
+
+// codeRange 错误码区间到 gRPC 状态码的映射，区间为闭区间 [min, max]
+type codeRange struct {
+	min  int
+	max  int
+	code codes.Code
+}
+
+// codeMapping gRPC 状态码映射表，精确错误码优先于区间
+var codeMapping = struct {
+	mu     sync.RWMutex
+	codes  map[int]codes.Code
+	ranges []codeRange
+}{
+	codes: map[int]codes.Code{
+		0: codes.OK,
+
+		10002: codes.DeadlineExceeded, // TimeoutError
+
+		20000: codes.Unauthenticated,   // Unauthorized
+		20001: codes.PermissionDenied,  // Forbidden
+		20002: codes.NotFound,          // UserNotFound
+		20003: codes.Unauthenticated,   // PasswordError
+		20004: codes.Unauthenticated,   // TokenExpired
+		20005: codes.PermissionDenied,  // AccountLocked
+		20006: codes.ResourceExhausted, // QuotaExceeded
+
+		30001: codes.Aborted,         // OperationConflict
+		30003: codes.NotFound,        // NoData
+		30004: codes.InvalidArgument, // ValidationFailed
+
+		40000: codes.Unavailable, // DBError
+		40001: codes.Unavailable, // CacheError
+		40002: codes.Unavailable, // NetworkError
+		40004: codes.Unavailable, // MessageQueueError
+
+		50002: codes.Unavailable, // ResourcePoolEmpty
+		50003: codes.Unavailable, // ServiceNotReady
+	},
+	ranges: []codeRange{
+		{min: 10000, max: 19999, code: codes.Internal},
+		{min: 20000, max: 29999, code: codes.PermissionDenied},
+		{min: 30000, max: 39999, code: codes.FailedPrecondition},
+		{min: 40000, max: 49999, code: codes.Unavailable},
+		{min: 50000, max: 59999, code: codes.Internal},
+	},
+}
+
+// SetCode 设置指定错误码对应的 gRPC 状态码
+func SetCode(code int, c codes.Code) {
+	codeMapping.mu.Lock()
+	defer codeMapping.mu.Unlock()
+	codeMapping.codes[code] = c
+}
+
+// SetCodeRange 设置错误码区间 [min, max] 对应的 gRPC 状态码
+// 后设置的区间优先匹配，可用于覆盖默认区间
+func SetCodeRange(min, max int, c codes.Code) {
+	codeMapping.mu.Lock()
+	defer codeMapping.mu.Unlock()
+	codeMapping.ranges = append([]codeRange{{min: min, max: max, code: c}}, codeMapping.ranges...)
+}
+
+// Code 返回错误对应的 gRPC 状态码
+// nil 返回 OK，非 kerr 错误及未匹配的错误码返回 Unknown
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	var e kerr.Error
+	if !errors.As(err, &e) {
+		return codes.Unknown
+	}
+	return codeOf(e.Code())
+}
+
+// codeOf 按错误码查找 gRPC 状态码
+func codeOf(code int) codes.Code {
+	codeMapping.mu.RLock()
+	defer codeMapping.mu.RUnlock()
+
+	if c, ok := codeMapping.codes[code]; ok {
+		return c
+	}
+	for _, r := range codeMapping.ranges {
+		if code >= r.min && code <= r.max {
+			return r.code
+		}
+	}
+	return codes.Unknown
+}
+
+// grpcCodes gRPC 状态码到错误码的反向映射，用于还原不带 ErrorInfo 的 status
+var grpcCodes = map[codes.Code]int{
+	codes.OK:                 0,
+	codes.InvalidArgument:    30004, // ValidationFailed
+	codes.OutOfRange:         30004, // ValidationFailed
+	codes.NotFound:           30003, // NoData
+	codes.AlreadyExists:      30001, // OperationConflict
+	codes.Aborted:            30001, // OperationConflict
+	codes.FailedPrecondition: 30000, // InvalidState
+	codes.Unauthenticated:    20000, // Unauthorized
+	codes.PermissionDenied:   20001, // Forbidden
+	codes.ResourceExhausted:  20006, // QuotaExceeded
+	codes.DeadlineExceeded:   10002, // TimeoutError
+	codes.Unavailable:        50003, // ServiceNotReady
+}
+
+// ToStatus 将错误转换为 gRPC status
+// status 的 message 按暴露级别选择：Debug 为脱敏后的内部错误信息，其他级别为显示信息；
+// 详情中的 ErrorInfo 携带错误码、错误详情和 kerr.ExposedFields 允许的结构化字段，
+// LocalizedMessage 携带显示信息；nil 返回 OK，已是 status 的错误原样返回，
+// context 的取消和超时转换为对应的状态码，其他非 kerr 错误按 SystemError 处理
+func ToStatus(err error) *status.Status {
+	return ToStatusContext(context.Background(), err)
+}
+
+// ToStatusContext 同 ToStatus，显示信息按 ctx 中 kctx 元数据记录的语言渲染
+func ToStatusContext(ctx context.Context, err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	var e kerr.Error
+	if !errors.As(err, &e) {
+		if st, ok := status.FromError(err); ok {
+			return st
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return status.FromContextError(err)
+		}
+		e = kerr.SystemError.Wrap(err)
+	}
+
+	info := &errdetails.ErrorInfo{
+		Reason:   "KERR_" + strconv.Itoa(e.Code()),
+		Domain:   ErrorDomain,
+		Metadata: map[string]string{MetadataCode: strconv.Itoa(e.Code())},
+	}
+	if fields := kerr.ExposedFields(e); len(fields) > 0 {
+		if data, jsonErr := json.Marshal(fields); jsonErr == nil {
+			info.Metadata[MetadataFields] = string(data)
+		}
+	}
+	if details := kerr.DetailsOf[kerr.Detail](e); len(details) > 0 {
+		if data, jsonErr := kerr.MarshalDetails(details); jsonErr == nil {
+			info.Metadata[MetadataDetails] = string(data)
+		}
+	}
+
+	display := kerr.DisplayFor(ctx, e)
+	msg := display
+	if kerr.CurrentExposure() == kerr.Debug {
+		msg = fmt.Sprint(kerr.Expose(e, kerr.Debug))
+	}
+	st := status.New(codeOf(e.Code()), msg)
+	withDetails, detailErr := st.WithDetails(info, &errdetails.LocalizedMessage{
+		Locale:  kerr.LocaleOf(ctx),
+		Message: display,
+	})
+	if detailErr != nil {
+		return st
+	}
+	return withDetails
+}
+
+// FromStatus 将 gRPC status 还原为 kerr 错误，nil 或 OK 返回 nil
+// 带有 kerr ErrorInfo 时还原错误码、结构化字段和错误详情，否则按 gRPC 状态码反查错误码；
+// 显示信息优先取 LocalizedMessage，其次取已注册错误码的显示信息
+func FromStatus(st *status.Status) kerr.Error {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+
+	code, ok := grpcCodes[st.Code()]
+	if !ok {
+		code = kerr.SystemError.Code()
+	}
+	var (
+		display string
+		fields  map[string]any
+		details []kerr.Detail
+	)
+	for _, d := range st.Details() {
+		switch v := d.(type) {
+		case *errdetails.ErrorInfo:
+			if v.GetDomain() != ErrorDomain {
+				continue
+			}
+			if c, err := strconv.Atoi(v.GetMetadata()[MetadataCode]); err == nil {
+				code = c
+			}
+			if raw, ok := v.GetMetadata()[MetadataFields]; ok {
+				_ = json.Unmarshal([]byte(raw), &fields)
+			}
+			if raw, ok := v.GetMetadata()[MetadataDetails]; ok {
+				details, _ = kerr.UnmarshalDetails([]byte(raw))
+			}
+		case *errdetails.LocalizedMessage:
+			display = v.GetMessage()
+		}
+	}
+	if display == "" {
+		if registered, ok := kerr.Lookup(code); ok {
+			display = registered.Display()
+		}
+	}
+
+	msg := st.Message()
+	if msg == "" {
+		msg = st.Code().String()
+	}
+	var e kerr.Error = kerr.New(code, msg)
+	if display != "" {
+		e = e.WithDisplay(display)
+	}
+	return e.WithFields(fields).WithDetails(details...)
+}
+
+// FromError 将 gRPC 客户端返回的错误还原为 kerr 错误
+// nil 返回 nil，不是 status 的错误原样返回
+func FromError(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	if e := FromStatus(st); e != nil {
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package kgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kearth/klib/kctx"
+	"github.com/kearth/klib/kerr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		want codes.Code
+	}{
+		{nil, codes.OK},
+		{kerr.Succ, codes.OK},
+		{kerr.ValidationFailed, codes.InvalidArgument},
+		{kerr.NoData.WithMessage("load order"), codes.NotFound},
+		{kerr.TimeoutError, codes.DeadlineExceeded},
+		{kerr.TokenExpired, codes.Unauthenticated},
+		{kerr.InvalidState, codes.FailedPrecondition},
+		{kerr.ThirdPartyError, codes.Unavailable},
+		{kerr.PanicError, codes.Internal},
+		{fmt.Errorf("wrapped: %w", kerr.QuotaExceeded), codes.ResourceExhausted},
+		{kerr.New(96100, "custom"), codes.Unknown},
+		{errors.New("plain"), codes.Unknown},
+	}
+	for _, c := range cases {
+		if got := Code(c.err); got != c.want {
+			t.Errorf("Code(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+
+	SetCode(96101, codes.Unimplemented)
+	SetCodeRange(96110, 96119, codes.DataLoss)
+	if got := Code(kerr.New(96101, "x")); got != codes.Unimplemented {
+		t.Errorf("expected custom code mapping, got %v", got)
+	}
+	if got := Code(kerr.New(96115, "x")); got != codes.DataLoss {
+		t.Errorf("expected custom range mapping, got %v", got)
+	}
+}
+
+func TestToStatusRoundTrip(t *testing.T) {
+	err := kerr.ValidationFailed.Wrap(errors.New("email: invalid")).
+		WithFields(map[string]any{"field": "email", "attempt": 2}).
+		WithDetails(kerr.FieldViolation{Field: "email", Reason: "invalid format"}, kerr.RetryInfo{Delay: time.Second})
+
+	setPublicFields(t, "field", "attempt")
+	st := ToStatus(err)
+	if st.Code() != codes.InvalidArgument || st.Message() != kerr.ValidationFailed.Display() {
+		t.Fatalf("unexpected status: %v %q", st.Code(), st.Message())
+	}
+
+	restored := FromStatus(st)
+	if !errors.Is(restored, kerr.ValidationFailed) {
+		t.Fatalf("expected ValidationFailed, got %v", restored)
+	}
+	if restored.Display() != kerr.ValidationFailed.Display() {
+		t.Errorf("unexpected display %q", restored.Display())
+	}
+	if restored.Fields()["field"] != "email" || restored.Fields()["attempt"] != float64(2) {
+		t.Errorf("unexpected fields: %v", restored.Fields())
+	}
+	if v := kerr.DetailsOf[kerr.FieldViolation](restored); len(v) != 1 || v[0].Field != "email" {
+		t.Errorf("unexpected field violations: %v", restored.Details())
+	}
+	if v := kerr.DetailsOf[kerr.RetryInfo](restored); len(v) != 1 || v[0].Delay != time.Second {
+		t.Errorf("unexpected retry info: %v", restored.Details())
+	}
+}
+
+// orderNotFound 测试用的注册错误码，在包初始化时注册一次，-count 重复执行测试时不会重复注册
+var orderNotFound = kerr.MustRegister(kerr.New(96120, "order not found").WithDisplay("订单不存在"))
+
+func init() {
+	kerr.AddMessages("en", map[int]string{96120: "Order {{.order_id}} not found"})
+}
+
+// setPublicFields 设置 Public 级别允许输出的字段，测试结束后恢复默认
+func setPublicFields(t *testing.T, keys ...string) {
+	kerr.SetPublicFields(keys...)
+	t.Cleanup(func() { kerr.SetPublicFields() })
+}
+
+// setExposure 设置暴露级别，测试结束后恢复
+func setExposure(t *testing.T, x kerr.Exposure) {
+	old := kerr.CurrentExposure()
+	kerr.SetExposure(x)
+	t.Cleanup(func() { kerr.SetExposure(old) })
+}
+
+func TestToStatusMessage(t *testing.T) {
+	err := kerr.DBError.Wrap(errors.New("dial tcp 10.0.0.1:3306: connection refused"))
+
+	setExposure(t, kerr.Public)
+	if st := ToStatus(err); st.Message() != kerr.DBError.Display() {
+		t.Errorf("public status message must be the display, got %q", st.Message())
+	}
+	ctx := kctx.New()
+	ctx.Set(kerr.LocaleKey, "en-US")
+	if st := ToStatusContext(ctx, orderNotFound.WithField("order_id", "A001")); st.Message() != "Order A001 not found" {
+		t.Errorf("status message must be localized, got %q", st.Message())
+	}
+	if st := ToStatus(errors.New("secret")); st.Message() != kerr.SystemError.Display() {
+		t.Errorf("foreign errors must not leak, got %q", st.Message())
+	}
+	if fields := FromStatus(ToStatus(err.WithField("host", "10.0.0.1"))).Fields(); len(fields) != 0 {
+		t.Errorf("public status must not carry fields outside the allowlist, got %v", fields)
+	}
+
+	kerr.SetExposure(kerr.Internal)
+	if st := ToStatus(err); st.Message() != kerr.DBError.Display() {
+		t.Errorf("internal status message must be the display, got %q", st.Message())
+	}
+
+	kerr.SetExposure(kerr.Debug)
+	if st := ToStatus(err); st.Message() != err.Error() {
+		t.Errorf("debug status message must be the error message, got %q", st.Message())
+	}
+}
+
+func TestToStatusLocalized(t *testing.T) {
+	ctx := kctx.New()
+	ctx.Set(kerr.LocaleKey, "en-US")
+	st := ToStatusContext(ctx, orderNotFound.WithField("order_id", "A001"))
+	if got := FromStatus(st).Display(); got != "Order A001 not found" {
+		t.Errorf("expected localized display, got %q", got)
+	}
+	if got := FromStatus(ToStatus(orderNotFound)).Display(); got != "订单不存在" {
+		t.Errorf("expected default display, got %q", got)
+	}
+}
+
+func TestToStatusForeign(t *testing.T) {
+	if st := ToStatus(nil); st.Code() != codes.OK || FromStatus(st) != nil {
+		t.Errorf("expected OK status for nil error")
+	}
+
+	native := status.Error(codes.AlreadyExists, "exists")
+	if st := ToStatus(native); st.Code() != codes.AlreadyExists || st.Message() != "exists" {
+		t.Errorf("expected status error to be kept, got %v", st)
+	}
+	if st := ToStatus(fmt.Errorf("call: %w", context.DeadlineExceeded)); st.Code() != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", st.Code())
+	}
+
+	st := ToStatus(errors.New("boom"))
+	if st.Code() != codes.Internal {
+		t.Errorf("expected Internal for foreign error, got %v", st.Code())
+	}
+	if !errors.Is(FromStatus(st), kerr.SystemError) {
+		t.Errorf("expected SystemError")
+	}
+}
+
+func TestFromStatusWithoutErrorInfo(t *testing.T) {
+	cases := []struct {
+		st   *status.Status
+		want kerr.Error
+	}{
+		{status.New(codes.NotFound, "missing"), kerr.NoData},
+		{status.New(codes.Unavailable, "down"), kerr.ServiceNotReady},
+		{status.New(codes.PermissionDenied, ""), kerr.Forbidden},
+		{status.New(codes.DataLoss, "lost"), kerr.SystemError},
+	}
+	for _, c := range cases {
+		e := FromStatus(c.st)
+		if !errors.Is(e, c.want) {
+			t.Errorf("FromStatus(%v) = %v, want code %d", c.st.Code(), e, c.want.Code())
+		}
+		if e.Display() != c.want.Display() {
+			t.Errorf("expected registered display %q, got %q", c.want.Display(), e.Display())
+		}
+	}
+	if got := FromStatus(status.New(codes.PermissionDenied, "")).Error(); got != codes.PermissionDenied.String() {
+		t.Errorf("expected code name as message, got %q", got)
+	}
+
+	if FromError(nil) != nil {
+		t.Errorf("expected nil")
+	}
+	plain := errors.New("plain")
+	if FromError(plain) != plain {
+		t.Errorf("expected non-status error to be returned unchanged")
+	}
+}
@@ -0,0 +1,388 @@
+package kerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CodePolicy 多错误聚合时错误码的选取策略，返回聚合错误对外使用的错误码
+type CodePolicy func(errs []error) int
+
+// DefaultCodePolicy Join 使用的默认错误码选取策略
+var DefaultCodePolicy CodePolicy = FirstCode
+
+// FirstCode 选取第一个 kerr 错误的错误码，全部为非 kerr 错误时使用 SystemError 的错误码
+func FirstCode(errs []error) int {
+	for _, err := range errs {
+		var e Error
+		if errors.As(err, &e) {
+			return e.Code()
+		}
+	}
+	return SystemError.Code()
+}
+
+// HighestSeverityCode 选取严重程度最高的错误码，严重程度相同时取靠前的错误
+func HighestSeverityCode(errs []error) int {
+	code, highest := SystemError.Code(), Severity(-1)
+	for _, err := range errs {
+		c := SystemError.Code()
+		var e Error
+		if errors.As(err, &e) {
+			c = e.Code()
+		}
+		if s := attrsOf(c).severity; s > highest {
+			code, highest = c, s
+		}
+	}
+	return code
+}
+
+// AggregateCode 固定使用指定的聚合错误码
+func AggregateCode(code int) CodePolicy {
+	return func([]error) int {
+		return code
+	}
+}
+
+// MultiError 聚合多个错误，本身也实现 Error 接口
+// Is/As 会逐个匹配子错误，Code 按 CodePolicy 选取
+type MultiError struct {
+	errs    []error
+	policy  CodePolicy
+	display string
+	stack   *stack
+	fields  map[string]any
+	notes   []string // 附加的上下文信息，由外到内排列
+	details []Detail
+}
+
+// clone 浅拷贝当前实例，用于派生不可变的新实例
+func (m *MultiError) clone() *MultiError {
+	c := *m
+	return &c
+}
+
+// Join 聚合多个错误，忽略 nil，全部为 nil 时返回 nil
+// 错误码使用 DefaultCodePolicy 选取
+func Join(errs ...error) Error {
+	return JoinWith(DefaultCodePolicy, errs...)
+}
+
+// JoinWith 按指定的错误码选取策略聚合多个错误
+// 没有附加信息的嵌套 MultiError 会被展开；附加了显示信息、上下文信息、字段、详情或调用栈的作为子错误保留
+func JoinWith(policy CodePolicy, errs ...error) Error {
+	flat := make([]error, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			flat = appendFlat(flat, err)
+		}
+	}
+	if len(flat) == 0 {
+		return nil
+	}
+	if policy == nil {
+		policy = FirstCode
+	}
+	return &MultiError{
+		errs:   flat,
+		policy: policy,
+	}
+}
+
+// appendFlat 追加子错误，没有附加信息的 MultiError 展开为其子错误，以免展开时丢失附加信息
+func appendFlat(errs []error, err error) []error {
+	if m, ok := err.(*MultiError); ok && m.bare() {
+		return append(errs, m.errs...)
+	}
+	return append(errs, err)
+}
+
+// bare 判断聚合错误是否没有附加信息，仅包含子错误
+func (m *MultiError) bare() bool {
+	return m.display == "" && m.stack == nil && len(m.fields) == 0 && len(m.notes) == 0 && len(m.details) == 0
+}
+
+// Errors 返回所有子错误的副本
+func (m *MultiError) Errors() []error {
+	errs := make([]error, len(m.errs))
+	copy(errs, m.errs)
+	return errs
+}
+
+// Error 返回所有子错误未脱敏的信息，以 "; " 分隔，附加的上下文信息作为前缀，脱敏在输出时进行，同 KError.Error
+func (m *MultiError) Error() string {
+	return m.rawText()
+}
+
+// rawText 返回未脱敏的错误信息
+func (m *MultiError) rawText() string {
+	msgs := make([]string, 0, len(m.errs))
+	for _, err := range m.errs {
+		msgs = append(msgs, rawTextOf(err))
+	}
+	if len(m.notes) == 0 {
+		return strings.Join(msgs, "; ")
+	}
+	return strings.Join(m.notes, ": ") + ": " + strings.Join(msgs, "; ")
+}
+
+// Code 返回按 CodePolicy 选取的错误码
+func (m *MultiError) Code() int {
+	return m.policy(m.errs)
+}
+
+// Display 返回错误显示信息，默认使用错误码对应子错误的显示信息
+func (m *MultiError) Display() string {
+	if m.display != "" {
+		return m.display
+	}
+	code := m.Code()
+	for _, err := range m.errs {
+		var e Error
+		if errors.As(err, &e) && e.Code() == code {
+			return e.Display()
+		}
+	}
+	if registered, ok := Lookup(code); ok {
+		return registered.Display()
+	}
+	return SystemError.Display()
+}
+
+// DisplayIn 返回指定语言的显示信息，按聚合错误码查找消息目录，WithDisplay 设置的显示信息优先
+func (m *MultiError) DisplayIn(locale string) string {
+	if m.display != "" {
+		return m.display
+	}
+	msg, ok := lookupMessage(locale, m.Code())
+	if !ok {
+		return m.Display()
+	}
+	return renderMessage(msg, m.Fields(), nil)
+}
+
+// WithDisplay 设置错误显示信息，返回新实例
+func (m *MultiError) WithDisplay(display string) Error {
+	c := m.clone()
+	c.display = display
+	return c
+}
+
+// WithMessage 附加格式化的上下文信息，返回新实例
+func (m *MultiError) WithMessage(format string, args ...any) Error {
+	c := m.clone()
+	c.notes = make([]string, 0, len(m.notes)+1)
+	c.notes = append(c.notes, fmt.Sprintf(format, args...))
+	c.notes = append(c.notes, m.notes...)
+	return c
+}
+
+// WithField 附加结构化字段，返回新实例
+func (m *MultiError) WithField(key string, val any) Error {
+	return m.WithFields(map[string]any{key: val})
+}
+
+// WithFields 批量附加结构化字段，返回新实例
+func (m *MultiError) WithFields(fields map[string]any) Error {
+	if len(fields) == 0 {
+		return m
+	}
+	c := m.clone()
+	c.fields = make(map[string]any, len(m.fields)+len(fields))
+	for k, v := range m.fields {
+		c.fields[k] = v
+	}
+	for k, v := range fields {
+		c.fields[k] = v
+	}
+	return c
+}
+
+// RedactedFields 返回脱敏后的 Fields，用于日志等输出
+func (m *MultiError) RedactedFields() map[string]any {
+	return RedactFields(m.Fields())
+}
+
+// Fields 返回合并后的结构化字段：子错误的字段按顺序合并，同名字段取靠前的子错误，聚合错误自身的字段覆盖子错误
+func (m *MultiError) Fields() map[string]any {
+	fields := make(map[string]any, len(m.fields))
+	for i := len(m.errs) - 1; i >= 0; i-- {
+		var child interface{ Fields() map[string]any }
+		if errors.As(m.errs[i], &child) {
+			for k, v := range child.Fields() {
+				fields[k] = v
+			}
+		}
+	}
+	for k, v := range m.fields {
+		fields[k] = v
+	}
+	return fields
+}
+
+// WithDetails 附加聚合错误自身的详情，返回新实例
+func (m *MultiError) WithDetails(details ...Detail) Error {
+	if len(details) == 0 {
+		return m
+	}
+	c := m.clone()
+	c.details = appendDetails(m.details, details)
+	return c
+}
+
+// Details 返回聚合错误自身的详情，不包含子错误的详情，收集全部详情请使用 DetailsOf
+func (m *MultiError) Details() []Detail {
+	return append([]Detail(nil), m.details...)
+}
+
+// Wrap 追加子错误，返回新实例，嵌套的 MultiError 同 JoinWith 处理
+func (m *MultiError) Wrap(err error) Error {
+	if err == nil {
+		return m
+	}
+	errs := make([]error, 0, len(m.errs)+1)
+	errs = append(errs, m.errs...)
+	c := m.clone()
+	c.errs = appendFlat(errs, err)
+	return c
+}
+
+// Unwrap 返回所有子错误，errors.Is/As 会逐个匹配子错误
+func (m *MultiError) Unwrap() []error {
+	return m.Errors()
+}
+
+// Is 与聚合错误码比较，子错误的匹配由 errors.Is 通过 Unwrap 完成
+func (m *MultiError) Is(target error) bool {
+	t, ok := target.(*KError)
+	return ok && t.code == m.Code()
+}
+
+// Stack 返回聚合时的调用栈
+func (m *MultiError) Stack() string {
+	if m.stack == nil {
+		return ""
+	}
+	return m.stack.String()
+}
+
+// callStack 返回聚合时收集的调用栈，用于计算指纹
+func (m *MultiError) callStack() *stack {
+	return m.stack
+}
+
+// WithStack 收集调用栈，返回新实例，已有调用栈时直接返回原实例
+func (m *MultiError) WithStack(skip ...int) Error {
+	if m.stack != nil {
+		return m
+	}
+	skipCount := 0 // 默认从 WithStack 的调用者开始
+	if len(skip) > 0 {
+		skipCount += skip[0]
+	}
+	c := m.clone()
+	c.stack = captureStack(skipCount)
+	return c
+}
+
+// Format 支持 fmt.Printf("%+v", err) 打印每个子错误及其堆栈，不受暴露级别影响，同 KError.Format
+func (m *MultiError) Format(f fmt.State, c rune) {
+	o := fullRender()
+	if c == 'v' && f.Flag('+') {
+		fmt.Fprint(f, m.verbose(o))
+		return
+	}
+	fmt.Fprint(f, m.text(o))
+}
+
+// text 返回 %s、%v 的输出
+func (m *MultiError) text(o renderOptions) string {
+	if o.exposure == Public {
+		return m.Display()
+	}
+	return redactText(m.rawText(), o.redact)
+}
+
+// verbose 返回 %+v 的输出：上下文信息、字段、详情、堆栈及每个子错误
+func (m *MultiError) verbose(o renderOptions) string {
+	if o.exposure == Public {
+		return fmt.Sprintf("%s (code=%d)\n", m.Display(), m.Code())
+	}
+	var b strings.Builder
+	for _, note := range m.notes {
+		fmt.Fprintf(&b, "%s: ", redactText(note, o.redact))
+	}
+	fmt.Fprintf(&b, "%d errors occurred (code=%d, display=%q)\n", len(m.errs), m.Code(), m.Display())
+	if len(m.fields) > 0 {
+		fmt.Fprintf(&b, "fields: %s\n", formatFields(redactFields(m.fields, o.redact)))
+	}
+	for _, d := range m.details {
+		fmt.Fprintf(&b, "detail: %s%+v\n", d.DetailType(), d)
+	}
+	if o.exposure == Debug && m.stack != nil {
+		b.WriteString(m.Stack())
+	}
+	for i, err := range m.errs {
+		fmt.Fprintf(&b, "[%d] %s\n", i, verboseOf(err, o))
+	}
+	return b.String()
+}
+
+// MarshalJSON 支持 JSON 序列化，errors 字段包含每个子错误，开启脱敏时错误信息和字段按脱敏规则处理
+// 与 KError.MarshalJSON 相同，输出完整信息，不受暴露级别影响
+func (m *MultiError) MarshalJSON() ([]byte, error) {
+	return m.marshalJSON(fullRender())
+}
+
+// marshalJSON 按输出选项序列化为 JSON
+func (m *MultiError) marshalJSON(o renderOptions) ([]byte, error) {
+	if o.exposure == Public {
+		return json.Marshal(kerrorJSON{Code: m.Code(), Msg: m.Display(), Display: m.Display()})
+	}
+
+	children := make([]json.RawMessage, 0, len(m.errs))
+	for _, err := range m.errs {
+		child, jsonErr := marshalOf(err, o)
+		if jsonErr != nil {
+			return nil, jsonErr
+		}
+		children = append(children, child)
+	}
+
+	var stackTrace []string
+	if o.exposure == Debug && m.stack != nil {
+		stackTrace = m.stack.lines()
+	}
+
+	return json.Marshal(struct {
+		Code        int               `json:"code"`
+		Fingerprint string            `json:"fingerprint"`
+		Msg         string            `json:"message"`
+		Display     string            `json:"display,omitempty"`
+		Errors      []json.RawMessage `json:"errors"`
+		Fields      map[string]any    `json:"fields,omitempty"`
+		Details     detailList        `json:"details,omitempty"`
+		Stack       []string          `json:"stack,omitempty"`
+	}{
+		Code:        m.Code(),
+		Fingerprint: m.Fingerprint(),
+		Msg:         redactText(m.rawText(), o.redact),
+		Display:     m.Display(),
+		Errors:      children,
+		Fields:      redactFields(m.fields, o.redact),
+		Details:     m.details,
+		Stack:       stackTrace,
+	})
+}
+
+// ToJSON 返回 JSON 字符串，内容与 MarshalJSON 相同
+func (m *MultiError) ToJSON() string {
+	b, err := m.marshalJSON(fullRender())
+	if err != nil {
+		return fmt.Sprintf(`{"code":%d,"message":%q}`, m.Code(), m.Display())
+	}
+	return string(b)
+}
@@ -0,0 +1,157 @@
+package kerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJoinNil(t *testing.T) {
+	if err := Join(); err != nil {
+		t.Errorf("expected nil for empty Join, got %v", err)
+	}
+	if err := Join(nil, nil); err != nil {
+		t.Errorf("expected nil when all errors are nil, got %v", err)
+	}
+}
+
+func TestJoinIsAs(t *testing.T) {
+	plain := errors.New("plain")
+	err := Join(NoData, nil, DBError.Wrap(plain), Join(ValidationFailed))
+
+	m, ok := err.(*MultiError)
+	if !ok {
+		t.Fatalf("expected *MultiError, got %T", err)
+	}
+	if len(m.Errors()) != 3 {
+		t.Fatalf("expected nested MultiError to be flattened into 3 errors, got %d", len(m.Errors()))
+	}
+
+	for _, target := range []error{NoData, DBError, ValidationFailed, plain} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected errors.Is to match child %v", target)
+		}
+	}
+	if errors.Is(err, TimeoutError) {
+		t.Errorf("expected errors.Is not to match TimeoutError")
+	}
+
+	var ke *KError
+	if !errors.As(err, &ke) || ke.Code() != NoData.Code() {
+		t.Errorf("expected errors.As to find the first *KError child, got %v", ke)
+	}
+	if children := m.Unwrap(); len(children) != 3 || errors.Unwrap(err) != nil {
+		t.Errorf("expected Unwrap() []error to return all children, got %v", children)
+	}
+}
+
+func TestJoinKeepsAnnotatedMulti(t *testing.T) {
+	batch := Join(NoData, DBError).WithField("batch", 7).WithMessage("import")
+	for name, err := range map[string]Error{
+		"Join": Join(ValidationFailed, batch),
+		"Wrap": Join(ValidationFailed).Wrap(batch),
+	} {
+		children := err.(*MultiError).Errors()
+		if len(children) != 2 || children[1] != batch {
+			t.Errorf("%s: annotated MultiError must be kept as a child, got %v", name, children)
+		}
+		if err.Fields()["batch"] != 7 || !strings.Contains(err.Error(), "import: no data; database error") {
+			t.Errorf("%s: annotations of the nested MultiError were lost: %v %q", name, err.Fields(), err.Error())
+		}
+		if !errors.Is(err, DBError) {
+			t.Errorf("%s: errors.Is must reach the nested children", name)
+		}
+	}
+	if got := Join(NoData).Wrap(Join(DBError, CacheError)).(*MultiError).Errors(); len(got) != 3 {
+		t.Errorf("bare MultiError must be flattened by Wrap, got %v", got)
+	}
+}
+
+func TestMultiErrorFields(t *testing.T) {
+	err := Join(
+		NoData.WithFields(map[string]any{"id": 1, "table": "orders"}),
+		DBError.WithFields(map[string]any{"id": 2, "host": "db1"}),
+	).WithField("table", "batch")
+
+	want := map[string]any{"id": 1, "host": "db1", "table": "batch"}
+	if got := err.Fields(); len(got) != len(want) || got["id"] != 1 || got["host"] != "db1" || got["table"] != "batch" {
+		t.Errorf("expected merged fields %v, got %v", want, got)
+	}
+}
+
+func TestJoinCodePolicy(t *testing.T) {
+	errs := []error{ValidationFailed, NetworkError, PanicError, SystemError}
+
+	if code := Join(errs...).Code(); code != ValidationFailed.Code() {
+		t.Errorf("expected first code %d, got %d", ValidationFailed.Code(), code)
+	}
+	if code := JoinWith(HighestSeverityCode, errs...).Code(); code != PanicError.Code() {
+		t.Errorf("expected highest severity code %d, got %d", PanicError.Code(), code)
+	}
+
+	batchFailed := New(96300, "batch failed").WithDisplay("批量操作失败")
+	err := JoinWith(AggregateCode(batchFailed.Code()), errs...)
+	if err.Code() != batchFailed.Code() {
+		t.Errorf("expected aggregate code %d, got %d", batchFailed.Code(), err.Code())
+	}
+	if !errors.Is(err, batchFailed) {
+		t.Errorf("expected errors.Is to match the aggregate code")
+	}
+	if err.WithDisplay("批量操作失败").Display() != "批量操作失败" {
+		t.Errorf("expected WithDisplay to override display")
+	}
+
+	if code := Join(errors.New("foreign")).Code(); code != SystemError.Code() {
+		t.Errorf("expected foreign errors to fall back to SystemError, got %d", code)
+	}
+}
+
+func TestMultiErrorFormatJSON(t *testing.T) {
+	err := Join(
+		NoData.WithStack(),
+		DBError.Wrap(errors.New("connection refused")).WithStack(),
+		errors.New("plain"),
+	).WithStack()
+
+	if got := err.Error(); got != "no data; database error: connection refused; plain" {
+		t.Errorf("unexpected Error(): %q", got)
+	}
+
+	output := fmt.Sprintf("%+v", err)
+	for _, want := range []string{"3 errors occurred", "[0] no data", "[1] database error", "[2] plain", "TestMultiErrorFormatJSON"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %%+v output to contain %q, got: %s", want, output)
+		}
+	}
+
+	var result struct {
+		Code   int `json:"code"`
+		Errors []struct {
+			Code  int      `json:"code"`
+			Msg   string   `json:"message"`
+			Stack []string `json:"stack"`
+		} `json:"errors"`
+	}
+	if jsonErr := json.Unmarshal([]byte(err.ToJSON()), &result); jsonErr != nil {
+		t.Fatalf("Unmarshal failed: %v", jsonErr)
+	}
+	if result.Code != NoData.Code() || len(result.Errors) != 3 {
+		t.Fatalf("unexpected JSON: %s", err.ToJSON())
+	}
+	if len(result.Errors[0].Stack) == 0 || len(result.Errors[1].Stack) == 0 {
+		t.Errorf("expected every kerr child to keep its own stack: %s", err.ToJSON())
+	}
+	if result.Errors[2].Msg != "plain" {
+		t.Errorf("expected foreign child message, got %q", result.Errors[2].Msg)
+	}
+}
+
+func TestMultiErrorWrap(t *testing.T) {
+	err := Join(NoData)
+	wrapped := err.Wrap(TimeoutError)
+	if !errors.Is(wrapped, TimeoutError) || errors.Is(err, TimeoutError) {
+		t.Errorf("expected Wrap to append a child on a new instance")
+	}
+}
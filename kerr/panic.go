@@ -0,0 +1,200 @@
+package kerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/kearth/klib/kctx"
+)
+
+// PanicKind panic 的分类
+type PanicKind string
+
+const (
+	PanicKindValue           PanicKind = "value"              // panic 任意值
+	PanicKindError           PanicKind = "error"              // panic 普通 error
+	PanicKindNilPointer      PanicKind = "nil_pointer"        // 空指针解引用
+	PanicKindIndexOutOfRange PanicKind = "index_out_of_range" // 下标越界
+	PanicKindSliceBounds     PanicKind = "slice_bounds"       // 切片边界越界
+	PanicKindDivideByZero    PanicKind = "divide_by_zero"     // 整数除零
+	PanicKindNilMap          PanicKind = "nil_map"            // 向 nil map 写入
+	PanicKindTypeAssertion   PanicKind = "type_assertion"     // 类型断言失败
+	PanicKindRuntime         PanicKind = "runtime"            // 其他运行时错误
+)
+
+// PanicValue 保存 recover 得到的 panic 值，作为 PanicError 的 cause
+type PanicValue struct {
+	Value any
+	Kind  PanicKind
+}
+
+// Error 返回 panic 信息
+func (p *PanicValue) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Unwrap panic 值为 error 时返回该 error，便于 errors.As 获取 runtime.Error
+func (p *PanicValue) Unwrap() error {
+	if err, ok := p.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// PanicHook panic 被恢复后的回调，可用于上报或记录日志
+type PanicHook func(ctx context.Context, err Error)
+
+var panicHook = struct {
+	mu   sync.RWMutex
+	hook PanicHook
+}{}
+
+// SetPanicHook 设置 panic 恢复后的回调，传入 nil 取消回调；输出到 klog 的回调见 kerrlog.LogPanic
+func SetPanicHook(hook PanicHook) {
+	panicHook.mu.Lock()
+	defer panicHook.mu.Unlock()
+	panicHook.hook = hook
+}
+
+// Recover 在 defer 中使用，将 panic 转换为 PanicError 写入 errp
+//
+//	func do() (err error) {
+//		defer kerr.Recover(&err)
+//		...
+//	}
+func Recover(errp *error) {
+	if r := recover(); r != nil {
+		setPanicError(errp, newPanicError(context.Background(), r))
+	}
+}
+
+// RecoverContext 同 Recover，ctx 会传递给 PanicHook，kctx 的 TraceID 会记录到错误字段中
+func RecoverContext(ctx context.Context, errp *error) {
+	if r := recover(); r != nil {
+		setPanicError(errp, newPanicError(ctx, r))
+	}
+}
+
+// SafeCall 执行 fn，fn 发生 panic 时返回 PanicError，否则返回 fn 的错误
+func SafeCall(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(context.Background(), r)
+		}
+	}()
+	return fn()
+}
+
+// SafeCallContext 同 SafeCall，ctx 会传递给 PanicHook
+func SafeCallContext(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(ctx, r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// setPanicError 写入 panic 错误，errp 为 nil 时忽略
+func setPanicError(errp *error, err Error) {
+	if errp != nil {
+		*errp = err
+	}
+}
+
+// newPanicError 根据 panic 值构造 PanicError，堆栈取自 panic 发生处
+// 不在此处上报，由返回错误的边界（如 kgrpc 拦截器、kunit.Call）统一上报一次
+func newPanicError(ctx context.Context, r any) Error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	kind := panicKind(r)
+	fields := map[string]any{"panic_kind": string(kind)}
+	if kc, ok := ctx.(kctx.Context); ok {
+		fields["trace_id"] = kc.TraceID()
+	}
+
+	e := PanicError.Wrap(&PanicValue{Value: r, Kind: kind}).WithFields(fields).(*KError).clone()
+	e.stack = panicStack()
+
+	panicHook.mu.RLock()
+	hook := panicHook.hook
+	panicHook.mu.RUnlock()
+	if hook != nil {
+		hook(ctx, e)
+	}
+	return e
+}
+
+// panicStack 收集 panic 发生处的调用栈，跳过 recover 所在帧及 runtime 内部帧
+// 帧数不超过 SetStackDepth 设置的深度
+func panicStack() *stack {
+	depth := stackDepth()
+
+	pcs := make([]uintptr, depth+32) // 额外的空间容纳 recover 及 runtime 内部帧
+	n := runtime.Callers(1, pcs)
+	pcs = pcs[:n]
+
+	for i := range pcs {
+		if frameFunction(pcs[i]) != "runtime.gopanic" {
+			continue
+		}
+		j := i + 1
+		for j < len(pcs) && isRuntimeFunction(frameFunction(pcs[j])) {
+			j++
+		}
+		pcs = pcs[j:]
+		break
+	}
+	if len(pcs) > depth {
+		pcs = pcs[:depth]
+	}
+	return &stack{pcs: pcs}
+}
+
+// frameFunction 返回 pc 对应的（最内层）函数名
+func frameFunction(pc uintptr) string {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return frame.Function
+}
+
+// isRuntimeFunction 判断是否为 runtime 内部函数
+func isRuntimeFunction(name string) bool {
+	return strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "internal/runtime/")
+}
+
+// panicKind 识别 panic 值的分类
+func panicKind(r any) PanicKind {
+	err, ok := r.(error)
+	if !ok {
+		return PanicKindValue
+	}
+	var re runtime.Error
+	if !errors.As(err, &re) {
+		return PanicKindError
+	}
+	var tae *runtime.TypeAssertionError
+	if errors.As(err, &tae) {
+		return PanicKindTypeAssertion
+	}
+
+	msg := re.Error()
+	switch {
+	case strings.Contains(msg, "nil pointer dereference"), strings.Contains(msg, "invalid memory address"):
+		return PanicKindNilPointer
+	case strings.Contains(msg, "index out of range"):
+		return PanicKindIndexOutOfRange
+	case strings.Contains(msg, "slice bounds out of range"):
+		return PanicKindSliceBounds
+	case strings.Contains(msg, "divide by zero"):
+		return PanicKindDivideByZero
+	case strings.Contains(msg, "nil map"):
+		return PanicKindNilMap
+	default:
+		return PanicKindRuntime
+	}
+}
@@ -0,0 +1,141 @@
+package kerr
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kearth/klib/kctx"
+)
+
+type panicUser struct {
+	name string
+}
+
+//go:noinline
+func panicNilPointer() error {
+	var u *panicUser
+	_ = u.name
+	return nil
+}
+
+//go:noinline
+func panicIndex(i int) error {
+	s := []int{1, 2, 3}
+	_ = s[i]
+	return nil
+}
+
+func recoverNilPointer() (err error) {
+	defer Recover(&err)
+	return panicNilPointer()
+}
+
+func TestRecover(t *testing.T) {
+	err := recoverNilPointer()
+	if !errors.Is(err, PanicError) {
+		t.Fatalf("expected PanicError, got %v", err)
+	}
+
+	var pv *PanicValue
+	if !errors.As(err, &pv) || pv.Kind != PanicKindNilPointer {
+		t.Fatalf("expected nil pointer panic value, got %v", pv)
+	}
+	var re runtime.Error
+	if !errors.As(err, &re) {
+		t.Errorf("expected the runtime.Error to be reachable through the cause")
+	}
+
+	ke := err.(*KError)
+	if ke.Fields()["panic_kind"] != string(PanicKindNilPointer) {
+		t.Errorf("expected panic_kind field, got %v", ke.Fields())
+	}
+
+	stack := ke.Stack()
+	first := strings.SplitN(stack, "\n", 2)[0]
+	if !strings.Contains(first, "panicNilPointer") {
+		t.Errorf("expected stack to start at the panic site, got:\n%s", stack)
+	}
+	if strings.Contains(stack, "kerr.Recover") || strings.Contains(stack, "runtime.gopanic") {
+		t.Errorf("expected recover and runtime frames to be skipped, got:\n%s", stack)
+	}
+}
+
+func TestSafeCall(t *testing.T) {
+	err := SafeCall(func() error {
+		return panicIndex(5)
+	})
+	var pv *PanicValue
+	if !errors.As(err, &pv) || pv.Kind != PanicKindIndexOutOfRange {
+		t.Fatalf("expected index out of range panic, got %v", err)
+	}
+
+	err = SafeCall(func() error {
+		panic("boom")
+	})
+	if !errors.As(err, &pv) || pv.Kind != PanicKindValue || pv.Value != "boom" {
+		t.Errorf("expected plain panic value, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "panic: boom") {
+		t.Errorf("expected Error() to contain the panic value, got %q", err.Error())
+	}
+
+	sentinel := errors.New("sentinel")
+	if err = SafeCall(func() error { return sentinel }); err != sentinel {
+		t.Errorf("expected fn error to be returned as is, got %v", err)
+	}
+	if err = SafeCall(func() error { return nil }); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
+
+func TestPanicKind(t *testing.T) {
+	var m map[string]int
+	var zero int
+	var v any = "str"
+	cases := []struct {
+		fn   func()
+		kind PanicKind
+	}{
+		{func() { m["a"] = 1 }, PanicKindNilMap},
+		{func() { _ = 1 / zero }, PanicKindDivideByZero},
+		{func() { _ = v.(int) }, PanicKindTypeAssertion},
+		{func() { s := []int{1}; _ = s[1:zero] }, PanicKindSliceBounds},
+		{func() { panic(errors.New("err")) }, PanicKindError},
+	}
+	for _, c := range cases {
+		err := SafeCall(func() error { c.fn(); return nil })
+		var pv *PanicValue
+		if !errors.As(err, &pv) || pv.Kind != c.kind {
+			t.Errorf("expected panic kind %s, got %v", c.kind, err)
+		}
+	}
+}
+
+func TestPanicHook(t *testing.T) {
+	var (
+		hookCtx context.Context
+		hookErr Error
+	)
+	SetPanicHook(func(ctx context.Context, err Error) {
+		hookCtx, hookErr = ctx, err
+	})
+	defer SetPanicHook(nil)
+
+	ctx := kctx.New()
+	err := SafeCallContext(ctx, func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	if hookErr == nil || hookErr != err {
+		t.Fatalf("expected hook to receive the PanicError, got %v", hookErr)
+	}
+	if hookCtx != ctx {
+		t.Errorf("expected hook to receive the call context")
+	}
+	if hookErr.Fields()["trace_id"] != ctx.TraceID() {
+		t.Errorf("expected trace_id field %q, got %v", ctx.TraceID(), hookErr.Fields())
+	}
+}
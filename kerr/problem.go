@@ -0,0 +1,245 @@
+package kerr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kearth/klib/kctx"
+)
+
+// ProblemContentType RFC 9457 Problem Details 的媒体类型
+const ProblemContentType = "application/problem+json"
+
+// Problem RFC 9457 Problem Details 文档
+// Extensions 中的成员与标准成员平铺在同一 JSON 对象中
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+// ProblemOptions ToProblem 的渲染选项
+type ProblemOptions struct {
+	// TypeBase type URI 前缀，type = TypeBase + 错误码，为空时 type 为 "about:blank"；
+	// ParseProblem 仅从带有该前缀的 type 中解析错误码
+	TypeBase string
+	// Debug 为 true 时，暴露级别为 Internal 也输出内部错误信息到 detail；暴露级别为 Debug 时总是输出，为 Public 时总不输出
+	Debug bool
+	// Context 用于获取 kctx TraceID 作为 instance，以及按语言渲染 title
+	Context context.Context
+}
+
+// problemMembers Problem Details 的标准成员，扩展成员不能与其重名
+var problemMembers = map[string]bool{
+	"type":     true,
+	"title":    true,
+	"status":   true,
+	"detail":   true,
+	"instance": true,
+}
+
+// ToProblem 将错误渲染为 Problem Details
+// type 由错误码生成，title 为显示信息，detail 按暴露级别和 opts.Debug 输出脱敏后的内部错误信息，
+// instance 为 kctx 的 TraceID，错误码、错误链上的详情及 ExposedFields 允许的结构化字段作为扩展成员输出
+func ToProblem(err error, opts ProblemOptions) *Problem {
+	var e Error
+	switch {
+	case err == nil:
+		e = Succ
+	case !errors.As(err, &e):
+		e = SystemError.Wrap(err)
+	}
+
+	p := &Problem{
+		Type:       "about:blank",
+		Title:      DisplayFor(opts.Context, e),
+		Status:     HTTPStatus(e),
+		Extensions: map[string]any{},
+	}
+	if opts.TypeBase != "" {
+		p.Type = opts.TypeBase + strconv.Itoa(e.Code())
+	}
+	if o := defaultRender(); err != nil && (o.exposure == Debug || opts.Debug && o.exposure == Internal) {
+		p.Detail = redactText(rawTextOf(err), o.redact)
+	}
+	if kc, ok := opts.Context.(kctx.Context); ok {
+		p.Instance = kc.TraceID()
+	}
+	for k, v := range ExposedFields(e) {
+		if !problemMembers[k] {
+			p.Extensions[k] = v
+		}
+	}
+	if details := allDetails(e); len(details) > 0 {
+		p.Extensions["details"] = detailList(details)
+	}
+	p.Extensions["code"] = e.Code()
+	return p
+}
+
+// WriteProblem 将错误以 application/problem+json 写入 HTTP 响应，与 WriteHTTP 相同只负责渲染，不上报错误
+func WriteProblem(w http.ResponseWriter, err error, opts ProblemOptions) {
+	p := ToProblem(err, opts)
+	body, _ := json.Marshal(p)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(body)
+}
+
+// MarshalJSON 将标准成员与扩展成员平铺序列化
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		if !problemMembers[k] {
+			m[k] = v
+		}
+	}
+	m["type"] = p.Type
+	if p.Title != "" {
+		m["title"] = p.Title
+	}
+	if p.Status != 0 {
+		m["status"] = p.Status
+	}
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON 解析 Problem Details，非标准成员放入 Extensions
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*p = Problem{Extensions: map[string]any{}}
+	targets := map[string]any{
+		"type":     &p.Type,
+		"title":    &p.Title,
+		"status":   &p.Status,
+		"detail":   &p.Detail,
+		"instance": &p.Instance,
+	}
+	for k, v := range raw {
+		if target, ok := targets[k]; ok {
+			// 类型不符的标准成员按 RFC 9457 的要求忽略
+			_ = json.Unmarshal(v, target)
+			continue
+		}
+		var ext any
+		if err := json.Unmarshal(v, &ext); err != nil {
+			return err
+		}
+		p.Extensions[k] = ext
+	}
+	return nil
+}
+
+// ParseProblem 将上游服务返回的 Problem Details 还原为 KError
+// 错误码优先取 type URI 中 opts.TypeBase 之后的部分（须与 ToProblem 使用相同的 TypeBase），
+// 其次取扩展成员 code，均无效时按 status 反查；未指定 TypeBase 或 type 不带该前缀时不从 type 解析，
+// 以免将其他服务 type URI 中的数字误认为错误码；
+// title 作为显示信息，detail 作为错误信息，扩展成员 details 还原为错误详情，
+// 其余扩展成员作为结构化字段
+//
+//	err, parseErr := kerr.ParseProblem(body, kerr.ProblemOptions{TypeBase: "https://errors.example.com/"})
+func ParseProblem(data []byte, opts ...ProblemOptions) (*KError, error) {
+	p := &Problem{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	return p.ToKError(opts...), nil
+}
+
+// ToKError 将 Problem Details 还原为 KError，错误码的解析方式同 ParseProblem
+func (p *Problem) ToKError(opts ...ProblemOptions) *KError {
+	var typeBase string
+	if len(opts) > 0 {
+		typeBase = opts[0].TypeBase
+	}
+	code, ok := problemCode(p, typeBase)
+	if !ok {
+		code = codeOfHTTPStatus(p.Status)
+	}
+
+	msg := p.Detail
+	if msg == "" {
+		msg = p.Title
+	}
+	if msg == "" {
+		msg = http.StatusText(p.Status)
+	}
+	e := New(code, msg)
+	if p.Title != "" {
+		e.display = p.Title
+	} else if registered, ok := Lookup(code); ok {
+		e.display = registered.Display()
+	}
+
+	if details, ok := problemDetails(p.Extensions["details"]); ok {
+		e.details = details
+	}
+	fields := make(map[string]any, len(p.Extensions))
+	for k, v := range p.Extensions {
+		if k != "code" && k != "details" {
+			fields[k] = v
+		}
+	}
+	if p.Instance != "" {
+		fields["instance"] = p.Instance
+	}
+	if len(fields) > 0 {
+		e.fields = fields
+	}
+	return e
+}
+
+// problemCode 从带有 typeBase 前缀的 type URI 或扩展成员 code 解析错误码
+func problemCode(p *Problem, typeBase string) (int, bool) {
+	if rest, ok := strings.CutPrefix(p.Type, typeBase); ok && typeBase != "" {
+		if code, err := strconv.Atoi(rest); err == nil {
+			return code, true
+		}
+	}
+	switch v := p.Extensions["code"].(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	case string:
+		if code, err := strconv.Atoi(v); err == nil {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// problemDetails 还原扩展成员 details 中的错误详情
+func problemDetails(v any) ([]Detail, bool) {
+	switch details := v.(type) {
+	case nil:
+		return nil, false
+	case detailList:
+		return details, true
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	var list detailList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, false
+	}
+	return list, true
+}
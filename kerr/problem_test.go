@@ -0,0 +1,133 @@
+package kerr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kearth/klib/kctx"
+)
+
+func TestToProblem(t *testing.T) {
+	ctx := kctx.New()
+	err := NoData.Wrap(errors.New("select * from orders")).
+		WithMessage("load order").
+		WithFields(map[string]any{"order_id": "A001", "status": "ignored"})
+	setPublicFields(t, "order_id", "status")
+
+	p := ToProblem(err, ProblemOptions{TypeBase: "https://errors.example.com/", Context: ctx})
+	if p.Type != "https://errors.example.com/30003" {
+		t.Errorf("unexpected type %q", p.Type)
+	}
+	if p.Title != NoData.Display() || p.Status != http.StatusNotFound {
+		t.Errorf("unexpected title/status: %q %d", p.Title, p.Status)
+	}
+	if p.Detail != "" {
+		t.Errorf("detail must be empty outside debug mode, got %q", p.Detail)
+	}
+	if p.Instance != ctx.TraceID() {
+		t.Errorf("expected instance %q, got %q", ctx.TraceID(), p.Instance)
+	}
+	if p.Extensions["order_id"] != "A001" || p.Extensions["code"] != 30003 {
+		t.Errorf("unexpected extensions: %v", p.Extensions)
+	}
+
+	var doc map[string]any
+	data, _ := json.Marshal(p)
+	if jsonErr := json.Unmarshal(data, &doc); jsonErr != nil {
+		t.Fatalf("Unmarshal failed: %v", jsonErr)
+	}
+	if doc["status"] != float64(404) || doc["order_id"] != "A001" || doc["code"] != float64(30003) {
+		t.Errorf("expected extensions to be flattened into the document, got %s", data)
+	}
+
+	setExposure(t, Public)
+	if public := ToProblem(err, ProblemOptions{Debug: true}); public.Detail != "" {
+		t.Errorf("detail must not be exposed at public level, got %q", public.Detail)
+	}
+	SetExposure(Internal)
+	if internal := ToProblem(err, ProblemOptions{}); internal.Detail != "" {
+		t.Errorf("detail must require opts.Debug at internal level, got %q", internal.Detail)
+	}
+	debug := ToProblem(err, ProblemOptions{Debug: true})
+	if debug.Type != "about:blank" || debug.Detail != err.Error() {
+		t.Errorf("unexpected debug problem: %+v", debug)
+	}
+	SetExposure(Debug)
+	if got := ToProblem(err, ProblemOptions{}); got.Detail != err.Error() {
+		t.Errorf("detail must be exposed at debug level, got %q", got.Detail)
+	}
+
+	foreign := ToProblem(errors.New("secret"), ProblemOptions{})
+	if foreign.Status != http.StatusInternalServerError || foreign.Extensions["code"] != SystemError.Code() {
+		t.Errorf("expected foreign error to be rendered as SystemError, got %+v", foreign)
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	setPublicFields(t, "field")
+	rec := httptest.NewRecorder()
+	WriteProblem(rec, ValidationFailed.WithField("field", "email"), ProblemOptions{TypeBase: "urn:kerr:"})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Errorf("expected problem content type, got %q", ct)
+	}
+
+	err, parseErr := ParseProblem(rec.Body.Bytes(), ProblemOptions{TypeBase: "urn:kerr:"})
+	if parseErr != nil {
+		t.Fatalf("ParseProblem failed: %v", parseErr)
+	}
+	if !errors.Is(err, ValidationFailed) || err.Display() != ValidationFailed.Display() {
+		t.Errorf("expected ValidationFailed, got %v", err)
+	}
+	if err.Fields()["field"] != "email" {
+		t.Errorf("expected extension members as fields, got %v", err.Fields())
+	}
+}
+
+func TestParseProblem(t *testing.T) {
+	opts := ProblemOptions{TypeBase: "https://errors.example.com/"}
+	cases := []struct {
+		doc  string
+		code int
+	}{
+		{`{"type":"https://errors.example.com/40002","title":"Network error","status":502}`, 40002},
+		{`{"type":"https://errors.example.com/40002","status":502,"code":30001}`, 40002},
+		{`{"type":"https://other.example.com/errors/404","title":"Not Found","status":404}`, NoData.Code()},
+		{`{"type":"https://other.example.com/v2/out-of-credit","status":403,"code":20006}`, 20006},
+		{`{"type":"https://errors.example.com/out-of-credit","status":403}`, Forbidden.Code()},
+		{`{"type":"about:blank","title":"Not Found","status":404}`, NoData.Code()},
+		{`{"title":"Out of credit","status":403,"code":"20006","balance":30}`, 20006},
+		{`{"status":"bad","title":"Gateway Timeout"}`, ThirdPartyError.Code()},
+	}
+	for _, c := range cases {
+		err, parseErr := ParseProblem([]byte(c.doc), opts)
+		if parseErr != nil {
+			t.Fatalf("ParseProblem(%s) failed: %v", c.doc, parseErr)
+		}
+		if err.Code() != c.code {
+			t.Errorf("ParseProblem(%s) code = %d, want %d", c.doc, err.Code(), c.code)
+		}
+	}
+
+	if err, _ := ParseProblem([]byte(`{"type":"https://errors.example.com/40002","status":404}`)); err.Code() != NoData.Code() {
+		t.Errorf("type must be ignored without TypeBase, got %d", err.Code())
+	}
+
+	err, _ := ParseProblem([]byte(`{"type":"urn:x:20006","title":"Out of credit","detail":"balance 30 < 50","instance":"/account/1","balance":30}`))
+	if err.Error() != "balance 30 < 50" || err.Display() != "Out of credit" {
+		t.Errorf("expected detail as message and title as display, got %q %q", err.Error(), err.Display())
+	}
+	if err.Fields()["balance"] != float64(30) || err.Fields()["instance"] != "/account/1" {
+		t.Errorf("unexpected fields: %v", err.Fields())
+	}
+
+	if _, parseErr := ParseProblem([]byte(`[]`)); parseErr == nil {
+		t.Errorf("expected error for non-object document")
+	}
+}
@@ -0,0 +1,139 @@
+package kerr
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// registry 全局错误码注册表，错误码 -> 规范错误实例及其属性
+var registry = struct {
+	mu    sync.RWMutex
+	codes map[int]Error
+	attrs map[int]attrs
+}{
+	codes: make(map[int]Error),
+	attrs: make(map[int]attrs),
+}
+
+// Option 注册选项，用于覆盖按错误码号段推导的默认属性
+type Option func(a *attrs)
+
+// WithCategory 指定错误分类，未通过其他选项显式指定的严重程度、可重试性按该分类推导
+func WithCategory(c Category) Option {
+	return func(a *attrs) {
+		a.category = c
+	}
+}
+
+// WithSeverity 指定严重程度
+func WithSeverity(s Severity) Option {
+	return func(a *attrs) {
+		a.severity = s
+	}
+}
+
+// WithRetryable 指定是否可重试，可重试的错误同时视为临时错误
+func WithRetryable(retryable bool) Option {
+	return func(a *attrs) {
+		a.retryable = retryable
+		if retryable {
+			a.temporary = true
+		}
+	}
+}
+
+// WithTemporary 指定是否为临时错误
+func WithTemporary(temporary bool) Option {
+	return func(a *attrs) {
+		a.temporary = temporary
+	}
+}
+
+// WithHTTPStatus 指定对应的 HTTP 状态码，等同于注册后调用 SetHTTPStatus
+func WithHTTPStatus(status int) Option {
+	return func(a *attrs) {
+		a.httpStatus = status
+	}
+}
+
+// Register 注册错误码，同一错误码只能注册一次
+// 若错误码已被注册，返回 NameRegistered 错误，原注册项保持不变
+func Register(err Error, opts ...Option) Error {
+	if err == nil {
+		return nil
+	}
+	code := err.Code()
+	// 先确定分类，再按分类推导默认属性，最后应用显式指定的属性
+	a := attrs{category: categoryOfCode(code)}
+	for _, opt := range opts {
+		opt(&a)
+	}
+	a = categoryAttrs(a.category, code)
+	for _, opt := range opts {
+		opt(&a)
+	}
+
+	registry.mu.Lock()
+	if existing, ok := registry.codes[code]; ok {
+		registry.mu.Unlock()
+		return NameRegistered.Wrap(fmt.Errorf("code %d already registered as %q", code, existing.Error()))
+	}
+	registry.codes[code] = err
+	registry.attrs[code] = a
+	registry.mu.Unlock()
+
+	if a.httpStatus != 0 {
+		SetHTTPStatus(code, a.httpStatus)
+	}
+	return nil
+}
+
+// MustRegister 注册错误码并原样返回，注册失败时 panic
+// 便于在 var 块中声明错误码：
+//
+//	OrderNotFound = kerr.MustRegister(kerr.New(60001, "order not found").WithDisplay("订单不存在"), kerr.WithHTTPStatus(404))
+func MustRegister(err Error, opts ...Option) Error {
+	if regErr := Register(err, opts...); regErr != nil {
+		panic(regErr)
+	}
+	return err
+}
+
+// Lookup 根据错误码查找已注册的规范错误实例
+// 未注册时返回 NameNotRegistered 错误和 false
+func Lookup(code int) (Error, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	if err, ok := registry.codes[code]; ok {
+		return err, true
+	}
+	return NameNotRegistered.Wrap(fmt.Errorf("code %d not registered", code)), false
+}
+
+// Registered 返回所有已注册的错误实例，按错误码升序排列
+func Registered() []Error {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	errs := make([]Error, 0, len(registry.codes))
+	for _, err := range registry.codes {
+		errs = append(errs, err)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Code() < errs[j].Code()
+	})
+	return errs
+}
+
+// attrsOf 返回错误码的属性，已注册的使用注册时的属性，否则按号段推导
+func attrsOf(code int) attrs {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	if a, ok := registry.attrs[code]; ok {
+		return a
+	}
+	return defaultAttrs(code)
+}
@@ -0,0 +1,69 @@
+package kerr
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuiltinRegistered(t *testing.T) {
+	err, ok := Lookup(30003)
+	if !ok {
+		t.Fatalf("expected builtin code 30003 to be registered")
+	}
+	if err != NoData {
+		t.Errorf("expected Lookup to return the canonical NoData, got %v", err)
+	}
+	if err.Display() != NoData.Display() {
+		t.Errorf("expected display %q, got %q", NoData.Display(), err.Display())
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	t.Cleanup(func() { unregister(96001) })
+	orderNotFound := New(96001, "order not found")
+	if err := Register(orderNotFound); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	err := Register(New(96001, "another order error"))
+	if !errors.Is(err, NameRegistered) {
+		t.Fatalf("expected NameRegistered for duplicate code, got %v", err)
+	}
+
+	got, _ := Lookup(96001)
+	if got != orderNotFound {
+		t.Errorf("duplicate registration must not replace the original, got %v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected MustRegister to panic on duplicate code")
+		}
+	}()
+	MustRegister(New(96001, "dup"))
+}
+
+func TestLookupNotRegistered(t *testing.T) {
+	err, ok := Lookup(96999)
+	if ok {
+		t.Fatalf("expected code 96999 to be unregistered")
+	}
+	if !errors.Is(err, NameNotRegistered) {
+		t.Errorf("expected NameNotRegistered, got %v", err)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	errs := Registered()
+	if len(errs) < 26 {
+		t.Fatalf("expected at least the builtin codes, got %d", len(errs))
+	}
+	for i := 1; i < len(errs); i++ {
+		if errs[i-1].Code() >= errs[i].Code() {
+			t.Fatalf("expected codes in ascending order, got %d before %d", errs[i-1].Code(), errs[i].Code())
+		}
+	}
+	if errs[0] != Succ {
+		t.Errorf("expected Succ to be the first registered error, got %v", errs[0])
+	}
+}
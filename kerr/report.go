@@ -0,0 +1,373 @@
+package kerr
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kearth/klib/kctx"
+)
+
+// ReportEvent 一次错误上报
+type ReportEvent struct {
+	Time        time.Time // 上报时间
+	Err         Error     // 上报的错误，非 kerr 错误已包装为 SystemError
+	Code        int       // 错误码
+	Fingerprint string    // 错误指纹，相同指纹的错误在去重窗口内只上报一次
+	TraceID     string    // kctx 的 TraceID
+	Suppressed  int       // 上一个去重窗口内被抑制的相同错误数量
+}
+
+// Reporter 错误上报器，由后台 goroutine 按顺序调用，实现无需并发安全
+type Reporter interface {
+	Report(ctx context.Context, event ReportEvent)
+}
+
+// ReporterFunc 函数形式的 Reporter
+type ReporterFunc func(ctx context.Context, event ReportEvent)
+
+// Report 实现 Reporter 接口
+func (f ReporterFunc) Report(ctx context.Context, event ReportEvent) {
+	f(ctx, event)
+}
+
+// reportQueueSize 异步上报队列长度，队列满时丢弃新的上报
+const reportQueueSize = 1024
+
+// maxDedupEntries 去重记录的最大数量，超出时淘汰最早上报的记录
+const maxDedupEntries = 4096
+
+// reportJob 上报队列中的任务，done 不为 nil 时为 Flush 的标记
+type reportJob struct {
+	ctx   context.Context
+	event ReportEvent
+	done  chan struct{}
+}
+
+// dedupEntry 去重窗口内的错误记录
+type dedupEntry struct {
+	fingerprint string
+	reported    time.Time
+	suppressed  int
+}
+
+// reporting 错误上报配置和状态
+var reporting = struct {
+	mu        sync.RWMutex
+	reporters []Reporter
+	filter    func(err Error) bool
+	rates     map[int]float64
+	window    time.Duration
+	queueMu   sync.RWMutex // 保护队列的创建和关闭，与 mu 分开以免阻塞分发
+	queue     chan reportJob
+	stopped   chan struct{}
+	dedupMu   sync.Mutex
+	seen      map[string]*list.Element // 指纹 -> order 中的 *dedupEntry
+	order     *list.List               // 按上报时间排列的去重记录，最早的在前
+}{
+	filter: DefaultReportFilter,
+	rates:  make(map[int]float64),
+	window: time.Minute,
+	seen:   make(map[string]*list.Element),
+	order:  list.New(),
+}
+
+// DefaultReportFilter 默认上报过滤：系统类错误（含 SystemError、PanicError）上报
+func DefaultReportFilter(err Error) bool {
+	return CategoryOf(err) == CategorySystem
+}
+
+// SetReporter 设置错误上报器，替换已有的上报器，不传参数时关闭上报
+//
+//	kerr.SetReporter(kerrlog.NewReporter())
+func SetReporter(reporters ...Reporter) {
+	reporting.mu.Lock()
+	defer reporting.mu.Unlock()
+	reporting.reporters = append([]Reporter(nil), reporters...)
+}
+
+// ResetReporting 恢复上报的默认配置：关闭上报器，恢复默认的过滤、采样率和去重窗口，并清空去重记录
+// 已提交的上报仍会分发；主要用于测试之间隔离上报状态
+//
+//	t.Cleanup(kerr.ResetReporting)
+func ResetReporting() {
+	reporting.mu.Lock()
+	reporting.reporters = nil
+	reporting.filter = DefaultReportFilter
+	reporting.rates = make(map[int]float64)
+	reporting.window = time.Minute
+	reporting.mu.Unlock()
+
+	reporting.dedupMu.Lock()
+	defer reporting.dedupMu.Unlock()
+	reporting.seen = make(map[string]*list.Element)
+	reporting.order.Init()
+}
+
+// SetReportFilter 设置需要上报的错误，传入 nil 恢复 DefaultReportFilter
+func SetReportFilter(filter func(err Error) bool) {
+	if filter == nil {
+		filter = DefaultReportFilter
+	}
+	reporting.mu.Lock()
+	defer reporting.mu.Unlock()
+	reporting.filter = filter
+}
+
+// SetSampleRate 设置错误码的采样率，取值 [0, 1]，未设置的错误码全部上报
+func SetSampleRate(code int, rate float64) {
+	reporting.mu.Lock()
+	defer reporting.mu.Unlock()
+	reporting.rates[code] = min(max(rate, 0), 1)
+}
+
+// SetDedupWindow 设置去重窗口，相同指纹的错误在窗口内只上报一次，默认为 1 分钟，0 表示不去重
+func SetDedupWindow(window time.Duration) {
+	reporting.mu.Lock()
+	defer reporting.mu.Unlock()
+	reporting.window = window
+}
+
+// Report 上报错误，经过过滤、按错误码采样和指纹去重后，由后台 goroutine 异步分发给上报器
+// nil 及未设置上报器时不做任何处理；非 kerr 错误按 SystemError 处理；上报队列满时丢弃
+func Report(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	reporting.mu.RLock()
+	reporters, filter, window := len(reporting.reporters), reporting.filter, reporting.window
+	rate, sampled := reporting.rates[CodeOf(err)]
+	reporting.mu.RUnlock()
+	if reporters == 0 {
+		return
+	}
+
+	var e Error
+	if !errors.As(err, &e) {
+		e = SystemError.Wrap(err)
+	}
+	if !filter(e) {
+		return
+	}
+	if sampled && rand.Float64() >= rate {
+		return
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	event := ReportEvent{
+		Time:        time.Now(),
+		Err:         e,
+		Code:        e.Code(),
+		Fingerprint: Fingerprint(e),
+	}
+	if traceID, ok := ctx.Value(kctx.TraceIDKey).(string); ok {
+		event.TraceID = traceID
+	}
+	dedup(&event, window, func() bool {
+		return enqueue(reportJob{ctx: context.WithoutCancel(ctx), event: event}, nil)
+	})
+}
+
+// dedup 按指纹去重后调用 submit 提交事件，窗口内重复的事件只计数
+// 仅在 submit 成功后记录上报时间，队列满被丢弃的事件不会抑制之后的相同错误
+func dedup(event *ReportEvent, window time.Duration, submit func() bool) {
+	if window <= 0 {
+		submit()
+		return
+	}
+	reporting.dedupMu.Lock()
+	defer reporting.dedupMu.Unlock()
+
+	elem, ok := reporting.seen[event.Fingerprint]
+	if ok {
+		entry := elem.Value.(*dedupEntry)
+		if event.Time.Sub(entry.reported) < window {
+			entry.suppressed++
+			return
+		}
+		event.Suppressed = entry.suppressed
+	}
+	if !submit() {
+		return
+	}
+	if ok {
+		reporting.order.Remove(elem)
+	}
+	reporting.seen[event.Fingerprint] = reporting.order.PushBack(&dedupEntry{fingerprint: event.Fingerprint, reported: event.Time})
+
+	// 清理过期记录，记录数超出上限时淘汰最早上报的记录
+	for front := reporting.order.Front(); front != nil; front = reporting.order.Front() {
+		entry := front.Value.(*dedupEntry)
+		if event.Time.Sub(entry.reported) < window && reporting.order.Len() <= maxDedupEntries {
+			break
+		}
+		reporting.order.Remove(front)
+		delete(reporting.seen, entry.fingerprint)
+	}
+}
+
+// enqueue 提交上报任务，首次使用时启动后台分发 goroutine
+// wait 为 nil 时队列满则丢弃任务，否则阻塞直到提交成功或 wait 被关闭
+func enqueue(job reportJob, wait <-chan struct{}) bool {
+	reporting.queueMu.RLock()
+	if reporting.queue == nil {
+		reporting.queueMu.RUnlock()
+		reporting.queueMu.Lock()
+		if reporting.queue == nil {
+			reporting.queue = make(chan reportJob, reportQueueSize)
+			reporting.stopped = make(chan struct{})
+			go dispatchReports(reporting.queue, reporting.stopped)
+		}
+		reporting.queueMu.Unlock()
+		reporting.queueMu.RLock()
+	}
+	defer reporting.queueMu.RUnlock()
+	if reporting.queue == nil {
+		return false // 并发关闭
+	}
+
+	if wait == nil {
+		select {
+		case reporting.queue <- job:
+			return true
+		default:
+			return false
+		}
+	}
+	select {
+	case reporting.queue <- job:
+		return true
+	case <-wait:
+		return false
+	}
+}
+
+// dispatchReports 按顺序将事件分发给上报器，上报器 panic 不影响后续事件
+func dispatchReports(queue chan reportJob, stopped chan struct{}) {
+	defer close(stopped)
+	for job := range queue {
+		if job.done != nil {
+			close(job.done)
+			continue
+		}
+		reporting.mu.RLock()
+		reporters := reporting.reporters
+		reporting.mu.RUnlock()
+		for _, r := range reporters {
+			func() {
+				defer func() { _ = recover() }()
+				r.Report(job.ctx, job.event)
+			}()
+		}
+	}
+}
+
+// FlushReports 等待已提交的上报全部分发完成
+func FlushReports(ctx context.Context) error {
+	done := make(chan struct{})
+	if !enqueue(reportJob{done: done}, ctx.Done()) {
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseReports 分发剩余的上报后停止后台 goroutine，并关闭实现了 io.Closer 的上报器
+// 关闭后再次调用 Report 会重新启动后台 goroutine
+func CloseReports() error {
+	reporting.queueMu.Lock()
+	queue, stopped := reporting.queue, reporting.stopped
+	reporting.queue, reporting.stopped = nil, nil
+	if queue != nil {
+		close(queue)
+	}
+	reporting.queueMu.Unlock()
+	if stopped != nil {
+		<-stopped
+	}
+
+	reporting.mu.RLock()
+	reporters := reporting.reporters
+	reporting.mu.RUnlock()
+	var errs []error
+	for _, r := range reporters {
+		if c, ok := r.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NDJSONReporter 以 NDJSON 格式逐行写出上报事件，可由本地采集器读取
+type NDJSONReporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewNDJSONReporter 创建写入 w 的 NDJSON 上报器
+func NewNDJSONReporter(w io.Writer) *NDJSONReporter {
+	return &NDJSONReporter{w: w}
+}
+
+// OpenNDJSONReporter 以追加方式打开文件，创建写入该文件的 NDJSON 上报器
+func OpenNDJSONReporter(path string) (*NDJSONReporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewNDJSONReporter(f), nil
+}
+
+// ndjsonEvent NDJSON 上报事件的结构
+type ndjsonEvent struct {
+	Time        time.Time       `json:"time"`
+	Code        int             `json:"code"`
+	Fingerprint string          `json:"fingerprint"`
+	TraceID     string          `json:"trace_id,omitempty"`
+	Suppressed  int             `json:"suppressed,omitempty"`
+	Error       json.RawMessage `json:"error"`
+}
+
+// Report 实现 Reporter 接口
+func (r *NDJSONReporter) Report(ctx context.Context, event ReportEvent) {
+	errJSON, err := json.Marshal(event.Err)
+	if err != nil {
+		return
+	}
+	line, err := json.Marshal(ndjsonEvent{
+		Time:        event.Time,
+		Code:        event.Code,
+		Fingerprint: event.Fingerprint,
+		TraceID:     event.TraceID,
+		Suppressed:  event.Suppressed,
+		Error:       errJSON,
+	})
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, _ = r.w.Write(append(line, '\n'))
+}
+
+// Close 底层 Writer 实现 io.Closer 时关闭
+func (r *NDJSONReporter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
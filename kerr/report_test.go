@@ -0,0 +1,241 @@
+package kerr
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kearth/klib/kctx"
+)
+
+// eventRecorder 记录上报事件的测试上报器
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []ReportEvent
+}
+
+func (r *eventRecorder) Report(ctx context.Context, event ReportEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) Events() []ReportEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ReportEvent(nil), r.events...)
+}
+
+// useRecorder 设置记录上报器，测试结束后恢复默认配置
+func useRecorder(t *testing.T, window time.Duration) *eventRecorder {
+	t.Helper()
+	rec := &eventRecorder{}
+	SetReporter(rec)
+	SetDedupWindow(window)
+	t.Cleanup(ResetReporting)
+	return rec
+}
+
+func flush(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := FlushReports(ctx); err != nil {
+		t.Fatalf("FlushReports failed: %v", err)
+	}
+}
+
+func TestReportFilter(t *testing.T) {
+	rec := useRecorder(t, 0)
+	ctx := kctx.New()
+
+	Report(ctx, nil)
+	Report(ctx, NoData)
+	Report(ctx, ValidationFailed.WithMessage("check order"))
+	Report(ctx, SystemError.Wrap(errors.New("disk full")))
+	Report(ctx, fmt.Errorf("plain: %w", errors.New("boom")))
+	Report(ctx, PanicError)
+	flush(t)
+
+	events := rec.Events()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 system events, got %d: %v", len(events), events)
+	}
+	for _, e := range events {
+		if e.TraceID != ctx.TraceID() || e.Fingerprint == "" || e.Time.IsZero() {
+			t.Errorf("unexpected event %+v", e)
+		}
+	}
+	if events[1].Code != SystemError.Code() || !errors.Is(events[1].Err, SystemError) {
+		t.Errorf("expected foreign error reported as SystemError, got %+v", events[1])
+	}
+	if events[2].Code != PanicError.Code() {
+		t.Errorf("expected PanicError, got %d", events[2].Code)
+	}
+
+	SetReportFilter(func(err Error) bool { return errors.Is(err, NoData) })
+	Report(ctx, NoData)
+	Report(ctx, SystemError)
+	flush(t)
+	if events := rec.Events(); len(events) != 4 || events[3].Code != NoData.Code() {
+		t.Errorf("expected custom filter to apply, got %v", events)
+	}
+}
+
+func TestReportSampling(t *testing.T) {
+	rec := useRecorder(t, 0)
+	SetReportFilter(func(Error) bool { return true })
+	SetSampleRate(96300, 0)
+	SetSampleRate(96301, 2) // 超出范围按 1 处理
+
+	for i := 0; i < 10; i++ {
+		Report(context.Background(), New(96300, "sampled out"))
+		Report(context.Background(), New(96301, "always"))
+	}
+	flush(t)
+
+	events := rec.Events()
+	if len(events) != 10 {
+		t.Fatalf("expected 10 events, got %d", len(events))
+	}
+	for _, e := range events {
+		if e.Code != 96301 {
+			t.Errorf("unexpected code %d", e.Code)
+		}
+	}
+}
+
+func TestReportDedup(t *testing.T) {
+	rec := useRecorder(t, 50*time.Millisecond)
+	cause := errors.New("connection reset")
+
+	for i := 0; i < 3; i++ {
+		Report(context.Background(), SystemError.Wrap(cause).WithMessage("attempt %d", i))
+	}
+	// 指纹不包含错误信息，只有错误类型不同才视为不同的错误
+	Report(context.Background(), SystemError.Wrap(errors.New("disk full")))
+	Report(context.Background(), SystemError.Wrap(&os.PathError{Op: "write", Path: "/data", Err: cause}))
+	flush(t)
+	if events := rec.Events(); len(events) != 2 {
+		t.Fatalf("expected duplicates to be suppressed, got %d events", len(events))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	Report(context.Background(), SystemError.Wrap(cause))
+	flush(t)
+	events := rec.Events()
+	if len(events) != 3 || events[2].Suppressed != 3 || events[2].Fingerprint != events[0].Fingerprint {
+		t.Errorf("expected report after window with suppressed count, got %+v", events)
+	}
+}
+
+func TestReportBoundaries(t *testing.T) {
+	rec := useRecorder(t, 0)
+	SetReporter(ReporterFunc(func(context.Context, ReportEvent) { panic("broken reporter") }), rec)
+
+	// 渲染和恢复 panic 不上报，由返回错误的边界上报一次
+	WriteHTTP(httptest.NewRecorder(), errors.New("db down"))
+	WriteProblem(httptest.NewRecorder(), ConfigError, ProblemOptions{})
+	err := func() (err error) {
+		defer Recover(&err)
+		panic("boom")
+	}()
+	flush(t)
+	if events := rec.Events(); len(events) != 0 {
+		t.Fatalf("renderers and Recover must not report, got %v", events)
+	}
+
+	ctx := kctx.New()
+	Report(ctx, err)
+	flush(t)
+	events := rec.Events()
+	if len(events) != 1 || events[0].Code != PanicError.Code() || events[0].TraceID != ctx.TraceID() {
+		t.Errorf("expected a single panic report with trace ID, got %v", events)
+	}
+}
+
+func TestReportDedupBounded(t *testing.T) {
+	useRecorder(t, time.Minute)
+	now := time.Now()
+
+	// 提交失败时不记录，之后的相同错误照常上报
+	event := ReportEvent{Time: now, Fingerprint: "dropped"}
+	dedup(&event, time.Minute, func() bool { return false })
+	submitted := false
+	dedup(&event, time.Minute, func() bool { submitted = true; return true })
+	if !submitted {
+		t.Errorf("a dropped event must not suppress the next one")
+	}
+
+	for i := 0; i < maxDedupEntries+10; i++ {
+		event := ReportEvent{Time: now, Fingerprint: fmt.Sprintf("fp-%d", i)}
+		dedup(&event, time.Minute, func() bool { return true })
+	}
+	reporting.dedupMu.Lock()
+	defer reporting.dedupMu.Unlock()
+	if len(reporting.seen) != maxDedupEntries || reporting.order.Len() != maxDedupEntries {
+		t.Errorf("dedup records must be capped at %d, got %d", maxDedupEntries, len(reporting.seen))
+	}
+	if _, ok := reporting.seen["fp-0"]; ok {
+		t.Errorf("the oldest record must be evicted first")
+	}
+}
+
+func TestNDJSONReporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.ndjson")
+	r, err := OpenNDJSONReporter(path)
+	if err != nil {
+		t.Fatalf("OpenNDJSONReporter failed: %v", err)
+	}
+	useRecorder(t, 0)
+	SetReporter(r)
+
+	ctx := kctx.New()
+	Report(ctx, SystemError.Wrap(errors.New("disk full")).WithField("volume", "/data"))
+	Report(ctx, TimeoutError)
+	if err := CloseReports(); err != nil {
+		t.Fatalf("CloseReports failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open report file: %v", err)
+	}
+	defer f.Close()
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[0]["code"] != float64(10000) || lines[0]["trace_id"] != ctx.TraceID() {
+		t.Errorf("unexpected line %v", lines[0])
+	}
+	if inner, ok := lines[0]["error"].(map[string]any); !ok || inner["fields"].(map[string]any)["volume"] != "/data" {
+		t.Errorf("expected error JSON in line, got %v", lines[0]["error"])
+	}
+
+	// 关闭后再次上报会重新启动分发
+	rec := &eventRecorder{}
+	SetReporter(rec)
+	Report(ctx, SystemError)
+	flush(t)
+	if len(rec.Events()) != 1 {
+		t.Errorf("expected dispatch to restart after CloseReports")
+	}
+}
@@ -0,0 +1,211 @@
+package kerr
+
+import (
+	"maps"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultStackDepth 默认的调用栈深度
+const defaultStackDepth = 32
+
+// stackRange 错误码区间的堆栈收集策略，区间为闭区间 [min, max]
+type stackRange struct {
+	min     int
+	max     int
+	capture bool
+}
+
+// stackConfig 调用栈收集策略，精确错误码优先于区间；发布后不再修改，修改时整体替换
+type stackConfig struct {
+	depth  int
+	codes  map[int]bool
+	ranges []stackRange
+}
+
+// defaultStackConfig 默认策略：不自动收集，仅在调用 WithStack 时收集
+var defaultStackConfig = &stackConfig{depth: defaultStackDepth}
+
+// stackPolicy 当前的调用栈收集策略，New 等热路径无锁读取，mu 仅用于串行化修改
+// config 为 nil 时使用 defaultStackConfig
+var stackPolicy struct {
+	mu     sync.Mutex
+	config atomic.Pointer[stackConfig]
+}
+
+// loadStackPolicy 返回当前的调用栈收集策略
+func loadStackPolicy() *stackConfig {
+	if c := stackPolicy.config.Load(); c != nil {
+		return c
+	}
+	return defaultStackConfig
+}
+
+// updateStackPolicy 复制当前策略，由 update 修改副本后整体替换
+func updateStackPolicy(update func(c *stackConfig)) {
+	stackPolicy.mu.Lock()
+	defer stackPolicy.mu.Unlock()
+	old := loadStackPolicy()
+	c := &stackConfig{
+		depth:  old.depth,
+		codes:  maps.Clone(old.codes),
+		ranges: slices.Clone(old.ranges),
+	}
+	update(c)
+	stackPolicy.config.Store(c)
+}
+
+// SetStackDepth 设置收集调用栈的最大帧数，小于等于 0 时恢复默认值 32
+func SetStackDepth(depth int) {
+	if depth <= 0 {
+		depth = defaultStackDepth
+	}
+	updateStackPolicy(func(c *stackConfig) {
+		c.depth = depth
+	})
+}
+
+// SetStackCapture 设置指定错误码在 New/Newf/Wrap 时是否自动收集调用栈，包初始化期间创建的错误不收集
+func SetStackCapture(code int, capture bool) {
+	updateStackPolicy(func(c *stackConfig) {
+		if c.codes == nil {
+			c.codes = make(map[int]bool)
+		}
+		c.codes[code] = capture
+	})
+}
+
+// SetStackCaptureRange 设置错误码区间 [min, max] 在 New/Newf/Wrap 时是否自动收集调用栈
+// 后设置的区间优先匹配，如仅为系统和框架错误收集调用栈：
+//
+//	kerr.SetStackCaptureRange(10000, 19999, true)
+//	kerr.SetStackCaptureRange(50000, 59999, true)
+func SetStackCaptureRange(min, max int, capture bool) {
+	updateStackPolicy(func(c *stackConfig) {
+		c.ranges = append([]stackRange{{min: min, max: max, capture: capture}}, c.ranges...)
+	})
+}
+
+// stackDepth 返回收集调用栈的最大帧数
+func stackDepth() int {
+	return loadStackPolicy().depth
+}
+
+// shouldCapture 判断错误码是否需要自动收集调用栈
+func shouldCapture(code int) bool {
+	c := loadStackPolicy()
+	if capture, ok := c.codes[code]; ok {
+		return capture
+	}
+	for _, r := range c.ranges {
+		if code >= r.min && code <= r.max {
+			return r.capture
+		}
+	}
+	return false
+}
+
+// autoStack 错误码按策略需要自动收集调用栈时，收集 New/Newf/Wrap 调用者处的调用栈
+// 包初始化期间（如包级变量声明的哨兵错误）不收集：初始化时的调用栈没有意义，
+// 且哨兵错误带有调用栈时，Wrap 和 WithStack 都会沿用它而不再收集
+func autoStack(code int) *stack {
+	if !shouldCapture(code) {
+		return nil
+	}
+	s := captureStack(1)
+	if s.inInit() {
+		return nil
+	}
+	return s
+}
+
+// inInit 判断调用栈是否在包初始化期间收集
+func (s *stack) inInit() bool {
+	for _, pc := range s.pcs {
+		if fn := runtime.FuncForPC(pc - 1); fn != nil && isInitFunction(fn.Name()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInitFunction 判断函数名是否为包初始化函数，如 "example.com/app.init"、"example.com/app.init.0"、
+// 包级变量初始化中的闭包 "example.com/app.init.func1" 及 runtime 中执行初始化的 "runtime.doInit1"
+func isInitFunction(name string) bool {
+	if strings.HasPrefix(name, "runtime.doInit") {
+		return true
+	}
+	name = name[strings.LastIndexByte(name, '/')+1:]
+	_, fn, _ := strings.Cut(name, ".")
+	return fn == "init" || strings.HasPrefix(fn, "init.")
+}
+
+// stack 调用栈，符号化和指纹延迟到首次使用时计算并缓存，派生的错误实例共享同一个 stack
+type stack struct {
+	pcs    []uintptr
+	once   sync.Once
+	frames []runtime.Frame
+
+	fpMu         sync.Mutex
+	fpGen        uint64            // 缓存的指纹对应的计算选项版本
+	fingerprints map[string]string // 错误码和错误链类型 -> 指纹
+}
+
+// captureStack 收集调用栈，skip 为 0 时从 captureStack 调用者的调用者开始
+func captureStack(skip int) *stack {
+	depth := stackDepth()
+
+	var buf [defaultStackDepth]uintptr
+	pcs := buf[:]
+	if depth > len(buf) {
+		pcs = make([]uintptr, depth)
+	}
+	n := runtime.Callers(skip+3, pcs[:depth])
+	return &stack{pcs: append([]uintptr(nil), pcs[:n]...)}
+}
+
+// Frames 返回符号化后的调用栈帧，内联函数会展开为独立的帧
+func (s *stack) Frames() []runtime.Frame {
+	s.once.Do(func() {
+		if len(s.pcs) == 0 {
+			return
+		}
+		frames := runtime.CallersFrames(s.pcs)
+		for {
+			frame, more := frames.Next()
+			s.frames = append(s.frames, frame)
+			if !more {
+				break
+			}
+		}
+	})
+	return s.frames
+}
+
+// String 返回调用栈文本，每帧一行，形如 "file:line function"
+func (s *stack) String() string {
+	var b strings.Builder
+	for _, frame := range s.Frames() {
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteByte(' ')
+		b.WriteString(frame.Function)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// lines 返回用于 JSON 序列化的调用栈，每帧形如 "file:line"
+func (s *stack) lines() []string {
+	frames := s.Frames()
+	lines := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		lines = append(lines, frame.File+":"+strconv.Itoa(frame.Line))
+	}
+	return lines
+}
@@ -0,0 +1,228 @@
+package kerr
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// topFrame 返回错误调用栈的第一帧函数名
+func topFrame(t *testing.T, err Error) string {
+	t.Helper()
+	s, ok := err.(interface{ callStack() *stack })
+	if !ok || s.callStack() == nil {
+		t.Fatalf("expected a captured stack on %v", err)
+	}
+	return s.callStack().Frames()[0].Function
+}
+
+func TestStackCapturePolicy(t *testing.T) {
+	t.Cleanup(snapshotStackPolicy())
+	sentinel := New(96400, "created before policy")
+	SetStackCaptureRange(96400, 96499, true)
+	SetStackCapture(96403, false)
+
+	if sentinel.stack != nil {
+		t.Fatalf("policy must not affect errors created before it was set")
+	}
+	const self = "github.com/kearth/klib/kerr.TestStackCapturePolicy"
+	if got := topFrame(t, New(96401, "new")); got != self {
+		t.Errorf("New captured stack from %s", got)
+	}
+	if got := topFrame(t, Newf(96402, "newf %d", 1)); got != self {
+		t.Errorf("Newf captured stack from %s", got)
+	}
+	wrapped := sentinel.Wrap(io.EOF)
+	if got := topFrame(t, wrapped); got != self {
+		t.Errorf("Wrap captured stack from %s", got)
+	}
+	if rewrapped := wrapped.Wrap(io.ErrUnexpectedEOF).(*KError); rewrapped.stack != wrapped.(*KError).stack {
+		t.Errorf("Wrap must keep an existing stack")
+	}
+	if New(96403, "excluded").stack != nil {
+		t.Errorf("exact code policy must override the range")
+	}
+	if New(96500, "outside").stack != nil || NoData.Wrap(io.EOF).(*KError).stack != nil {
+		t.Errorf("codes outside the policy must not capture stacks")
+	}
+}
+
+func TestStackPolicyRestore(t *testing.T) {
+	restore := snapshotStackPolicy()
+	SetStackCaptureRange(96420, 96429, true)
+	SetStackCapture(96421, false)
+	SetStackDepth(4)
+	restore()
+
+	if shouldCapture(96420) || stackDepth() != defaultStackDepth {
+		t.Errorf("expected the snapshot to restore the previous policy")
+	}
+}
+
+// initSentinel 包初始化期间按策略创建的哨兵错误
+var initSentinel = func() *KError {
+	restore := snapshotStackPolicy()
+	defer restore()
+	SetStackCapture(96430, true)
+	return New(96430, "init-time sentinel")
+}()
+
+func TestStackSkipsInit(t *testing.T) {
+	t.Cleanup(snapshotStackPolicy())
+	if initSentinel.stack != nil {
+		t.Fatalf("errors created during package initialization must not capture stacks")
+	}
+
+	SetStackCapture(96430, true)
+	if got := topFrame(t, initSentinel.Wrap(io.EOF)); got != "github.com/kearth/klib/kerr.TestStackSkipsInit" {
+		t.Errorf("Wrap of an init-time sentinel captured stack from %s", got)
+	}
+	if got := topFrame(t, initSentinel.WithStack()); got != "github.com/kearth/klib/kerr.TestStackSkipsInit" {
+		t.Errorf("WithStack of an init-time sentinel captured stack from %s", got)
+	}
+}
+
+func TestWithStackIdempotent(t *testing.T) {
+	err := NoData.WithStack()
+	if again := err.WithStack(); again != err {
+		t.Errorf("WithStack must return the original instance when a stack exists")
+	}
+	multi := Join(NoData).WithStack()
+	if again := multi.WithStack(); again != multi {
+		t.Errorf("MultiError.WithStack must return the original instance when a stack exists")
+	}
+}
+
+func TestIsInitFunction(t *testing.T) {
+	for name, want := range map[string]bool{
+		"example.com/app.init":           true,
+		"example.com/app.init.0":         true,
+		"example.com/app.init.func1":     true,
+		"runtime.doInit1":                true,
+		"example.com/app/initdb.Open":    false,
+		"example.com/app.(*Server).init": false,
+		"example.com/app.initialize":     false,
+		"example.com/app.TestInit.func1": false,
+	} {
+		if got := isInitFunction(name); got != want {
+			t.Errorf("isInitFunction(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// recurse 递归 n 层后收集调用栈
+func recurse(n int) Error {
+	if n == 0 {
+		return SystemError.WithStack()
+	}
+	return recurse(n - 1)
+}
+
+func TestStackDepth(t *testing.T) {
+	t.Cleanup(snapshotStackPolicy())
+
+	SetStackDepth(2)
+	if frames := recurse(10).(*KError).stack.Frames(); len(frames) != 2 {
+		t.Errorf("expected 2 frames, got %d", len(frames))
+	}
+	func() (err error) {
+		defer Recover(&err)
+		panic("depth")
+	}()
+
+	SetStackDepth(100)
+	if frames := recurse(60).(*KError).stack.Frames(); len(frames) <= 60 {
+		t.Errorf("expected more than 60 frames, got %d", len(frames))
+	}
+}
+
+func TestStackSymbolization(t *testing.T) {
+	err := recurse(0).WithMessage("lazy")
+	ke := err.(*KError)
+
+	// 符号化结果被缓存，派生的实例共享同一调用栈
+	first := ke.stack.Frames()
+	if &first[0] != &ke.stack.Frames()[0] {
+		t.Errorf("expected frames to be cached")
+	}
+	if ke.WithField("k", "v").(*KError).stack != ke.stack {
+		t.Errorf("derived errors must share the stack")
+	}
+
+	var doc struct {
+		Stack []string `json:"stack"`
+	}
+	if jsonErr := json.Unmarshal([]byte(err.ToJSON()), &doc); jsonErr != nil {
+		t.Fatalf("Unmarshal failed: %v", jsonErr)
+	}
+	lines := strings.Split(strings.TrimSpace(err.Stack()), "\n")
+	if len(doc.Stack) != len(lines) {
+		t.Fatalf("JSON stack has %d frames, Stack() has %d", len(doc.Stack), len(lines))
+	}
+	for i := range lines {
+		if !strings.HasPrefix(lines[i], doc.Stack[i]+" ") {
+			t.Errorf("frame %d differs: %q vs %q", i, doc.Stack[i], lines[i])
+		}
+	}
+	if !strings.HasSuffix(lines[0], "kerr.recurse") || !strings.Contains(doc.Stack[0], "stack_test.go:") {
+		t.Errorf("unexpected top frame %q", lines[0])
+	}
+
+	if (&stack{}).String() != "" || len((&stack{}).lines()) != 0 {
+		t.Errorf("expected empty stack output")
+	}
+}
+
+var benchErr error
+
+func BenchmarkNew(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchErr = New(96410, "bench")
+	}
+}
+
+func BenchmarkWrap(b *testing.B) {
+	cause := errors.New("cause")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchErr = DBError.Wrap(cause)
+	}
+}
+
+func BenchmarkWrapCapture(b *testing.B) {
+	sentinel := New(96411, "bench")
+	b.Cleanup(snapshotStackPolicy())
+	SetStackCapture(96411, true)
+	cause := errors.New("cause")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchErr = sentinel.Wrap(cause)
+	}
+}
+
+func BenchmarkWithStack(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchErr = SystemError.WithStack()
+	}
+}
+
+func BenchmarkWithStackFormat(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchErr = errors.New(SystemError.WithStack().Stack())
+	}
+}
+
+func BenchmarkMarshalJSON(b *testing.B) {
+	err := SystemError.Wrap(io.EOF).WithStack().WithField("order_id", "A001")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, jsonErr := json.Marshal(err); jsonErr != nil {
+			b.Fatal(jsonErr)
+		}
+	}
+}
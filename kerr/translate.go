@@ -0,0 +1,137 @@
+package kerr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// TranslateRule 错误转换规则，匹配时返回转换后的 kerr 错误
+type TranslateRule func(err error) (Error, bool)
+
+// MatchValue 按错误值匹配（errors.Is），匹配时转换为 to 并包装原错误
+func MatchValue(target error, to Error) TranslateRule {
+	return func(err error) (Error, bool) {
+		if errors.Is(err, target) {
+			return to.Wrap(err), true
+		}
+		return nil, false
+	}
+}
+
+// MatchType 按错误类型匹配（errors.As），匹配时转换为 to 并包装原错误
+//
+//	kerr.AddTranslateRule(kerr.MatchType[*mysql.MySQLError](kerr.DBError))
+func MatchType[T error](to Error) TranslateRule {
+	return func(err error) (Error, bool) {
+		var target T
+		if errors.As(err, &target) {
+			return to.Wrap(err), true
+		}
+		return nil, false
+	}
+}
+
+// MatchFunc 按判断函数匹配，匹配时转换为 to 并包装原错误
+func MatchFunc(match func(err error) bool, to Error) TranslateRule {
+	return func(err error) (Error, bool) {
+		if match(err) {
+			return to.Wrap(err), true
+		}
+		return nil, false
+	}
+}
+
+// ruleGroup 一次 AddTranslateRule 添加的规则，组内按添加顺序匹配
+type ruleGroup struct {
+	rules []TranslateRule
+}
+
+// translateRules 错误转换规则表，应用规则优先于默认规则
+var translateRules = struct {
+	mu       sync.RWMutex
+	groups   []*ruleGroup // 后添加的在前
+	defaults []TranslateRule
+}{
+	defaults: []TranslateRule{
+		MatchValue(sql.ErrNoRows, NoData),
+		MatchFunc(isTimeout, TimeoutError), // context.DeadlineExceeded 及网络超时
+		MatchValue(context.Canceled, NetworkError),
+		MatchValue(os.ErrNotExist, NetworkError),
+		MatchType[*net.OpError](NetworkError),
+		MatchValue(io.ErrUnexpectedEOF, InternalIOError),
+	},
+}
+
+// AddTranslateRule 添加应用自定义的转换规则，返回移除这批规则的函数
+// 匹配顺序：自定义规则优先于默认规则；后一次调用添加的规则优先于先前添加的规则，可用于覆盖；
+// 同一次调用中的规则按传入顺序匹配，靠前的优先
+//
+//	remove := kerr.AddTranslateRule(kerr.MatchValue(sql.ErrNoRows, OrderNotFound))
+//	t.Cleanup(remove)
+func AddTranslateRule(rules ...TranslateRule) (remove func()) {
+	g := &ruleGroup{rules: append([]TranslateRule(nil), rules...)}
+	translateRules.mu.Lock()
+	defer translateRules.mu.Unlock()
+	translateRules.groups = append([]*ruleGroup{g}, translateRules.groups...)
+
+	return func() {
+		translateRules.mu.Lock()
+		defer translateRules.mu.Unlock()
+		groups := make([]*ruleGroup, 0, len(translateRules.groups))
+		for _, other := range translateRules.groups {
+			if other != g {
+				groups = append(groups, other)
+			}
+		}
+		translateRules.groups = groups
+	}
+}
+
+// Translate 将标准库、驱动等返回的错误转换为 kerr 错误，原错误作为 cause 保留
+// nil 返回 nil；err 本身是 kerr 错误时原样返回；err 包装了 kerr 错误（如 fmt.Errorf("load: %w", kerr.NoData)）时，
+// 同 Wrapf 沿用错误链上第一个 kerr 错误的错误码和显示信息，err 作为 cause 保留外层包装；未匹配任何规则时转换为 SystemError
+//
+// 默认规则：
+//   - sql.ErrNoRows -> NoData
+//   - context.DeadlineExceeded 及实现 Timeout() bool 的超时错误 -> TimeoutError
+//   - context.Canceled、os.ErrNotExist、*net.OpError -> NetworkError
+//   - io.ErrUnexpectedEOF -> InternalIOError
+func Translate(err error) Error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(Error); ok {
+		return e
+	}
+	var inner Error
+	if errors.As(err, &inner) {
+		msg := inner.Display()
+		if ke, ok := inner.(*KError); ok {
+			msg = ke.msg
+		}
+		e := New(inner.Code(), msg)
+		e.display = inner.Display()
+		e.cause = err
+		return e
+	}
+
+	translateRules.mu.RLock()
+	var rules []TranslateRule
+	for _, g := range translateRules.groups {
+		rules = append(rules, g.rules...)
+	}
+	rules = append(rules, translateRules.defaults...)
+	translateRules.mu.RUnlock()
+
+	for _, rule := range rules {
+		if translated, ok := rule(err); ok {
+			return translated
+		}
+	}
+	return SystemError.Wrap(err)
+}
@@ -0,0 +1,119 @@
+package kerr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+// driverError 测试用的驱动错误类型
+type driverError struct {
+	number int
+}
+
+func (e *driverError) Error() string { return fmt.Sprintf("driver error %d", e.number) }
+
+// timeoutError 实现 Timeout() bool 的测试错误
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestTranslateDefaults(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	cases := []struct {
+		err  error
+		want Error
+	}{
+		{sql.ErrNoRows, NoData},
+		{fmt.Errorf("query order: %w", sql.ErrNoRows), NoData},
+		{context.DeadlineExceeded, TimeoutError},
+		{&net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, TimeoutError},
+		{context.Canceled, NetworkError},
+		{&os.PathError{Op: "open", Path: "/tmp/x.sock", Err: os.ErrNotExist}, NetworkError},
+		{dialErr, NetworkError},
+		{io.ErrUnexpectedEOF, InternalIOError},
+		{errors.New("unknown"), SystemError},
+	}
+	for _, c := range cases {
+		got := Translate(c.err)
+		if got.Code() != c.want.Code() {
+			t.Errorf("Translate(%v) code = %d, want %d", c.err, got.Code(), c.want.Code())
+		}
+		if !errors.Is(got, c.err) || errors.Unwrap(got) != c.err {
+			t.Errorf("Translate(%v) must keep the original error as cause", c.err)
+		}
+	}
+
+	if Translate(nil) != nil {
+		t.Errorf("expected nil for nil error")
+	}
+	if got := Translate(NoData); got != NoData { //kerrlint:ignore kerrcompare kerr 错误原样返回
+		t.Errorf("expected kerr error to be returned unchanged")
+	}
+	wrapped := fmt.Errorf("load: %w", DBError.Wrap(sql.ErrNoRows))
+	if got := Translate(wrapped); got.Code() != DBError.Code() {
+		t.Errorf("expected existing kerr code to win over rules, got %d", got.Code())
+	}
+
+	// 外层包装作为 cause 保留，同 Wrapf
+	outer := fmt.Errorf("load user 42: %w", NoData)
+	got := Translate(outer)
+	if got.Code() != NoData.Code() || got.Display() != NoData.Display() || errors.Unwrap(got) != outer {
+		t.Errorf("expected the outer error to be kept as cause, got %v", got)
+	}
+	if got.Error() != "no data: load user 42: no data" || !errors.Is(got, NoData) {
+		t.Errorf("unexpected translated error %q", got.Error())
+	}
+}
+
+func TestTranslateCustomRules(t *testing.T) {
+	errLocked := errors.New("row locked")
+	conflict := New(96200, "duplicate key")
+	t.Cleanup(AddTranslateRule(
+		MatchValue(errLocked, OperationConflict),
+		MatchFunc(func(err error) bool {
+			var d *driverError
+			return errors.As(err, &d) && d.number == 1062
+		}, conflict),
+		MatchType[*driverError](DBError),
+	))
+
+	if got := Translate(fmt.Errorf("update: %w", errLocked)); !errors.Is(got, OperationConflict) {
+		t.Errorf("expected OperationConflict, got %v", got)
+	}
+	if got := Translate(&driverError{number: 1213}); !errors.Is(got, DBError) {
+		t.Errorf("expected DBError, got %v", got)
+	}
+	// 同一批规则按传入顺序匹配
+	got := Translate(&driverError{number: 1062})
+	if got.Code() != 96200 || !strings.HasPrefix(got.Error(), "duplicate key: driver error 1062") {
+		t.Errorf("expected earlier rule in the same call to win, got %v", got)
+	}
+
+	// 自定义规则优先于默认规则，后添加的规则优先于先前添加的规则
+	remove := AddTranslateRule(MatchValue(sql.ErrNoRows, New(96201, "order not found")), MatchType[*driverError](InvalidState))
+	if got := Translate(sql.ErrNoRows); got.Code() != 96201 {
+		t.Errorf("expected custom rule to override default, got %d", got.Code())
+	}
+	if got := Translate(&driverError{number: 1062}); !errors.Is(got, InvalidState) {
+		t.Errorf("expected later call to win, got %v", got)
+	}
+
+	// 移除后恢复先前的规则，重复移除不影响其他规则
+	remove()
+	remove()
+	if got := Translate(sql.ErrNoRows); got.Code() != NoData.Code() {
+		t.Errorf("expected default rule after removal, got %d", got.Code())
+	}
+	if got := Translate(&driverError{number: 1062}); got.Code() != 96200 {
+		t.Errorf("expected earlier rules to stay, got %v", got)
+	}
+}
@@ -3,6 +3,7 @@ package klog
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -22,13 +23,14 @@ func TestLogString(t *testing.T) {
 			Level:    "INFO",
 			LevelInt: glog.LEVEL_INFO,
 			TraceID:  "test-trace-123",
+			SpanID:   "00f067aa0ba902b7",
 			Body:     []any{"user", "login"},
 			Add:      map[string]string{"user_id": "1001"},
 		}
 		result := log.String()
 		assert.Contains(t, result, "2024-01-01 12:00:00 UTC")
 		assert.Contains(t, result, "[INFO]")
-		assert.Contains(t, result, "test-trace-123")
+		assert.Contains(t, result, "test-trace-123 00f067aa0ba902b7")
 		assert.Contains(t, result, "user_id=1001")
 		assert.Contains(t, result, "userlogin")
 	})
@@ -221,3 +223,57 @@ func TestColorPrint(t *testing.T) {
 	ColorPrint(ctx, Yellow, "yellow ", "log")
 	Notice(ctx, "green ", "log")
 }
+
+// fieldsErr 携带结构化字段的测试错误
+type fieldsErr struct {
+	fields map[string]any
+}
+
+func (e fieldsErr) Error() string          { return "query failed" }
+func (e fieldsErr) Fields() map[string]any { return e.fields }
+
+// TestFormatBodyErrorFields 测试错误结构化字段的输出
+func TestFormatBodyErrorFields(t *testing.T) {
+	t.Parallel()
+
+	err := fieldsErr{fields: map[string]any{"table": "users", "request_id": "req-1"}}
+	result := formatBody([]any{"db: ", err}, nil)
+	assert.Contains(t, result, "db: query failed")
+	assert.Contains(t, result, " request_id=req-1 table=users")
+}
+
+// formattedErr 实现 fmt.Formatter 的测试错误，Format 输出脱敏后的信息
+type formattedErr struct{}
+
+func (formattedErr) Error() string                 { return "dial mysql://root:hunter2@db" }
+func (formattedErr) Format(f fmt.State, verb rune) { _, _ = fmt.Fprint(f, "dial mysql://root:***@db") }
+
+// TestFormatBodyErrorFormatter 测试错误按 fmt.Formatter 输出
+func TestFormatBodyErrorFormatter(t *testing.T) {
+	t.Parallel()
+
+	result := formatBody([]any{"query: ", formattedErr{}}, nil)
+	assert.Equal(t, "query: dial mysql://root:***@db", result)
+}
+
+// TestDefaultHandlerSpan 测试日志输出 StartSpan 开启的子 span ID
+func TestDefaultHandlerSpan(t *testing.T) {
+	t.Parallel()
+
+	ctx := kctx.New()
+	spanCtx, end := kctx.StartSpan(ctx, "handler")
+	defer end()
+
+	input := &glog.HandlerInput{
+		Level:       glog.LEVEL_INFO,
+		LevelFormat: "INFO",
+		Time:        time.Now(),
+		Values:      []any{"in span"},
+		Buffer:      &bytes.Buffer{},
+	}
+	DefaultHandler(spanCtx, input)
+	result := input.Buffer.String()
+
+	assert.Contains(t, result, ctx.TraceID()+" "+spanCtx.SpanContext().SpanID.String())
+	assert.NotContains(t, result, ctx.SpanContext().SpanID.String())
+}
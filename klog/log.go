@@ -3,6 +3,7 @@ package klog
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
@@ -38,6 +39,7 @@ func DefaultHandler(ctx context.Context, in *glog.HandlerInput) {
 		Level:    in.LevelFormat,
 		LevelInt: in.Level,
 		TraceID:  newCtx.TraceID(),
+		SpanID:   spanIDOf(newCtx),
 		Body:     in.Values,
 		Add:      newCtx.Values(),
 	}).String())
@@ -56,6 +58,7 @@ type Log struct {
 	Level    string
 	LevelInt int
 	TraceID  string
+	SpanID   string
 	Body     []any
 	Add      map[string]string
 }
@@ -71,6 +74,50 @@ func Logger(name ...string) *glog.Logger {
 	return g.Log()
 }
 
+// spanIDOf 返回上下文的 span ID，没有 span 时返回空字符串
+func spanIDOf(ctx kctx.Context) string {
+	if sc := ctx.SpanContext(); sc.SpanID.IsValid() {
+		return sc.SpanID.String()
+	}
+	return ""
+}
+
+// fieldsError 携带结构化字段的错误，如 kerr.Error
+type fieldsError interface {
+	error
+	Fields() map[string]any
+}
+
+// redactedFieldsError 提供脱敏后结构化字段的错误，如 kerr.Error，优先于 Fields 使用
+type redactedFieldsError interface {
+	error
+	RedactedFields() map[string]any
+}
+
+// formatFields 按键排序格式化错误的结构化字段，形如 " k1=v1 k2=v2"
+func formatFields(fields map[string]any) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(fmt.Sprintf(" %s=%s", k, gconv.String(fields[k])))
+	}
+	return b.String()
+}
+
+// formatValue 格式化日志主体中的值
+// 错误通过 fmt 输出，实现了 fmt.Formatter 的错误（如 kerr.Error）按自身规则输出，kerr 错误的敏感信息会被脱敏
+func formatValue(v any) string {
+	if err, ok := v.(error); ok {
+		return fmt.Sprint(err)
+	}
+	return gconv.String(v)
+}
+
 // formatBody 格式化日志主体
 func formatBody(body []any, add map[string]string) string {
 	var b strings.Builder
@@ -89,7 +136,14 @@ func formatBody(body []any, add map[string]string) string {
 			body = body[1:]
 		}
 		for _, v := range body {
-			b.WriteString(gconv.String(v))
+			b.WriteString(formatValue(v))
+		}
+		for _, v := range body {
+			if re, ok := v.(redactedFieldsError); ok {
+				b.WriteString(formatFields(re.RedactedFields()))
+			} else if fe, ok := v.(fieldsError); ok {
+				b.WriteString(formatFields(fe.Fields()))
+			}
 		}
 	}
 	if colorName != 0 {
@@ -106,10 +160,11 @@ func (l *Log) String() string {
 		return formatBody(l.Body, l.Add)
 	}
 	return fmt.Sprintf(
-		"%s %s %s %s",
+		"%s %s %s %s %s",
 		l.Time,
 		color.New(color.Attribute(colorMaps[l.LevelInt])).Sprint("["+l.Level+"]"),
 		kutil.If[string](l.TraceID == "", "-", l.TraceID),
+		kutil.If[string](l.SpanID == "", "-", l.SpanID),
 		formatBody(l.Body, l.Add))
 }
 
@@ -1,6 +1,8 @@
 package kunit
 
 import (
+	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -103,3 +105,35 @@ func TestUnit_Setup(t *testing.T) {
 	// 测试默认 Setup 方法（返回 nil）
 	assert.NoError(t, u.Setup(kctx.New()))
 }
+
+func TestUnit_CallReport(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []kerr.ReportEvent
+	)
+	kerr.SetReporter(kerr.ReporterFunc(func(ctx context.Context, event kerr.ReportEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	t.Cleanup(kerr.ResetReporting)
+
+	ctx := kctx.New()
+	u := NewUnit("report", func(ctx kctx.Context, input ...any) (any, kerr.Error) {
+		return nil, kerr.ConfigError.WithMessage("load %v", input...)
+	})
+	_, err := u.Call(ctx, "app.yaml")
+	assert.Error(t, err)
+	// 业务错误不在默认上报范围内
+	_, _ = NewUnit("business", func(ctx kctx.Context, input ...any) (any, kerr.Error) {
+		return nil, kerr.NoData
+	}).Call(ctx)
+	assert.NoError(t, kerr.FlushReports(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, kerr.ConfigError.Code(), events[0].Code)
+		assert.Equal(t, ctx.TraceID(), events[0].TraceID)
+	}
+}
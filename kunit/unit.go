@@ -1,6 +1,7 @@
 package kunit
 
 import (
+	"errors"
 	"time"
 
 	"github.com/kearth/klib/kctx"
@@ -101,9 +102,12 @@ func (u *unit) Call(ctx kctx.Context, input ...any) (any, kerr.Error) {
 		u.err = kerr.DependencyMissing
 	}
 	u.end = time.Now()
-	if u.err != kerr.DependencyMissing {
+	if !errors.Is(u.err, kerr.DependencyMissing) {
 		u.cost = u.end.Sub(u.start)
 	}
+	if err != nil {
+		kerr.Report(ctx, err) // 系统类错误自动上报，未设置上报器时不做处理
+	}
 	return output, err
 }
 